@@ -0,0 +1,84 @@
+package cron
+
+import (
+	"fmt"
+	"time"
+)
+
+// BusinessDaySchedule activates at a time of day on the Nth business day of
+// every month. Business days are Monday to Friday, excluding the days for which
+// Holiday, if set, returns true. Days are evaluated in the location of the time
+// passed to Next, so that they follow the Cron's time zone.
+type BusinessDaySchedule struct {
+	N            int
+	Hour, Minute int
+
+	// Holiday reports whether the given day, at midnight, is a holiday.
+	Holiday func(day time.Time) bool
+}
+
+// BusinessDayOfMonth returns a schedule that activates at hour:min on the nth
+// business day of every month, e.g. BusinessDayOfMonth(3, 9, 0) for 09:00 on
+// the third business day. Set its Holiday to skip holidays as well as
+// weekends.
+//
+// It panics if n is not within 1-23, the most business days a month can have,
+// or hour or min is out of range.
+func BusinessDayOfMonth(n, hour, min int) *BusinessDaySchedule {
+	if n < 1 || n > 23 || hour < 0 || hour > 23 || min < 0 || min > 59 {
+		panic("cron: BusinessDayOfMonth requires n in 1-23, hour in 0-23 and min in 0-59")
+	}
+	return &BusinessDaySchedule{N: n, Hour: hour, Minute: min}
+}
+
+// IsBusinessDay reports whether day is a business day for the schedule.
+func (s *BusinessDaySchedule) IsBusinessDay(day time.Time) bool {
+	if wd := day.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return false
+	}
+	return s.Holiday == nil || !s.Holiday(day)
+}
+
+// String describes the schedule, e.g. "business day 3 of every month at
+// 09:00", followed by "except holidays" if Holiday is set.
+func (s *BusinessDaySchedule) String() string {
+	str := fmt.Sprintf("business day %d of every month at %02d:%02d", s.N, s.Hour, s.Minute)
+	if s.Holiday != nil {
+		str += " except holidays"
+	}
+	return str
+}
+
+// Next returns the first activation after t. If no month within five years has
+// enough business days, it returns the zero time.
+func (s *BusinessDaySchedule) Next(t time.Time) time.Time {
+	y, m, _ := t.Date()
+	for i := 0; i < 12*5; i++ {
+		if next, ok := s.inMonth(y, m+time.Month(i), t.Location()); ok && next.After(t) {
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+// RandomNext returns the next activation like Next, delayed by up to
+// delayRange seconds.
+func (s *BusinessDaySchedule) RandomNext(t time.Time, delayRange int) time.Time {
+	return jitter(s, t, delayRange, cryptoIntn)
+}
+
+// inMonth returns the activation in the given month, if it has N business
+// days.
+func (s *BusinessDaySchedule) inMonth(year int, month time.Month, loc *time.Location) (time.Time, bool) {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	count := 0
+	for day := first; day.Month() == first.Month(); day = day.AddDate(0, 0, 1) {
+		if !s.IsBusinessDay(day) {
+			continue
+		}
+		if count++; count == s.N {
+			return time.Date(day.Year(), day.Month(), day.Day(), s.Hour, s.Minute, 0, 0, loc), true
+		}
+	}
+	return time.Time{}, false
+}
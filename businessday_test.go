@@ -0,0 +1,45 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBusinessDayOfMonth(t *testing.T) {
+	sched := BusinessDayOfMonth(3, 9, 0)
+
+	// June 2024 starts on a Saturday, so its third business day is Wednesday
+	// the 5th, across the weekend.
+	for _, c := range []struct{ from, expected string }{
+		{"Fri May 31 12:00 2024", "Wed Jun 5 09:00 2024"},
+		{"Wed Jun 5 09:00 2024", "Wed Jul 3 09:00 2024"},
+		{"Sun Dec 1 00:00 2024", "Wed Dec 4 09:00 2024"},
+	} {
+		if actual := sched.Next(getTime(c.from)); !actual.Equal(getTime(c.expected)) {
+			t.Errorf("from %s: expected %s, got %v", c.from, c.expected, actual)
+		}
+	}
+
+	// With July 2nd as a holiday, the third business day moves to the 4th.
+	sched.Holiday = func(day time.Time) bool {
+		return day.Month() == time.July && day.Day() == 2
+	}
+	if actual := sched.Next(getTime("Sat Jun 15 00:00 2024")); !actual.Equal(getTime("Thu Jul 4 09:00 2024")) {
+		t.Errorf("expected the holiday to be skipped, got %v", actual)
+	}
+}
+
+func TestBusinessDayOfMonthUnsatisfiable(t *testing.T) {
+	sched := BusinessDayOfMonth(23, 0, 0)
+	sched.Holiday = func(time.Time) bool { return true }
+	if next := sched.Next(getTime("Mon Jul 9 00:00 2012")); !next.IsZero() {
+		t.Errorf("expected the zero time, got %v", next)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for n out of range")
+		}
+	}()
+	BusinessDayOfMonth(24, 0, 0)
+}
@@ -0,0 +1,167 @@
+package cron
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// config is the serialized form of a Cron's configuration.
+type config struct {
+	Location string        `json:"location"`
+	Entries  []entryConfig `json:"entries"`
+}
+
+// entryConfig is the serialized form of an entry.
+type entryConfig struct {
+	Name        string `json:"name"`
+	Spec        string `json:"spec"`
+	DelayRange  int    `json:"delayRange,omitempty"`
+	Description string `json:"description,omitempty"`
+	Group       string `json:"group,omitempty"`
+	Paused      bool   `json:"paused,omitempty"`
+	RunInline   bool   `json:"runInline,omitempty"`
+	Location    string `json:"location,omitempty"`
+
+	SkipIfRunning  bool `json:"skipIfRunning,omitempty"`
+	QueueIfRunning bool `json:"queueIfRunning,omitempty"`
+	MaxQueued      int  `json:"maxQueued,omitempty"`
+
+	MaxRetries   int    `json:"maxRetries,omitempty"`
+	RetryBackoff string `json:"retryBackoff,omitempty"`
+
+	RemoveWhenDone bool `json:"removeWhenDone,omitempty"`
+}
+
+// MarshalConfig serializes the configuration of the Cron to JSON: its
+// location and, for every entry in the order they were added, the name, spec,
+// location of its own if any, delay range, description, group, paused state,
+// overlap policy, retries and whether it is removed when done. Jobs
+// themselves are not serialized; LoadConfig re-attaches them by name. Neither
+// is Entry.RunIf, which is a func.
+//
+// Entries whose schedule cannot be represented as a spec, see
+// Entry.SpecString, such as those added with AddOnceFunc or with a schedule
+// from At, Between or Union, are left out, and each one is logged. It returns
+// an error if an entry has no name, as its job could not be told apart from
+// the others when loading.
+func (c *Cron) MarshalConfig() ([]byte, error) {
+	cfg := config{
+		Location: c.Location().String(),
+		Entries:  []entryConfig{},
+	}
+	var err error
+	c.do(func() {
+		entries := append([]*Entry(nil), c.entries...)
+		sort.Slice(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+		for _, e := range entries {
+			if e.Name == "" {
+				err = fmt.Errorf("cron: entry with spec %q has no name", e.Spec)
+				return
+			}
+			spec, ok := e.SpecString()
+			if !ok {
+				c.logf("cron: not exporting %q: its schedule has no spec", e.Name)
+				continue
+			}
+			var location, backoff string
+			if e.Location != nil {
+				location = e.Location.String()
+			}
+			if e.RetryBackoff != 0 {
+				backoff = e.RetryBackoff.String()
+			}
+			cfg.Entries = append(cfg.Entries, entryConfig{
+				Name:           e.Name,
+				Spec:           spec,
+				DelayRange:     e.DelayRange,
+				Description:    e.Description,
+				Group:          e.Group,
+				Paused:         e.Paused,
+				RunInline:      e.RunInline,
+				Location:       location,
+				SkipIfRunning:  e.SkipIfRunning,
+				QueueIfRunning: e.QueueIfRunning,
+				MaxQueued:      e.MaxQueued,
+				MaxRetries:     e.MaxRetries,
+				RetryBackoff:   backoff,
+				RemoveWhenDone: e.RemoveWhenDone,
+			})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(cfg)
+}
+
+// LoadConfig returns a new, stopped Cron with the configuration serialized by
+// MarshalConfig. resolve is called with each entry's name and returns the Job
+// to run for it, or nil if there is none, which is an error.
+//
+// Specs are parsed with the default parser, so entries that were added with
+// a parser for another dialect do not round-trip.
+func LoadConfig(data []byte, resolve func(name string) Job) (*Cron, error) {
+	var cfg config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(cfg.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewWithLocation(loc)
+	for _, ec := range cfg.Entries {
+		if ec.Name == "" {
+			return nil, fmt.Errorf("cron: entry with spec %q has no name", ec.Spec)
+		}
+		schedule, err := Parse(ec.Spec)
+		if err != nil {
+			return nil, fmt.Errorf("cron: entry %q: %v", ec.Name, err)
+		}
+		var entryLoc *time.Location
+		if ec.Location != "" {
+			if entryLoc, err = time.LoadLocation(ec.Location); err != nil {
+				return nil, fmt.Errorf("cron: entry %q: %v", ec.Name, err)
+			}
+			if _, zoned := scheduleLocation(schedule); !zoned {
+				schedule = InLocation(schedule, entryLoc)
+			}
+		}
+		var backoff time.Duration
+		if ec.RetryBackoff != "" {
+			if backoff, err = time.ParseDuration(ec.RetryBackoff); err != nil {
+				return nil, fmt.Errorf("cron: entry %q: %v", ec.Name, err)
+			}
+		}
+		job := resolve(ec.Name)
+		if job == nil {
+			return nil, fmt.Errorf("cron: no job for entry %q", ec.Name)
+		}
+		err = c.addEntry(&Entry{
+			Schedule:    schedule,
+			Job:         job,
+			Name:        ec.Name,
+			Description: ec.Description,
+			DelayRange:  ec.DelayRange,
+			Spec:        ec.Spec,
+			Group:       ec.Group,
+			Paused:      ec.Paused,
+			RunInline:   ec.RunInline,
+			Location:    entryLoc,
+
+			SkipIfRunning:  ec.SkipIfRunning,
+			QueueIfRunning: ec.QueueIfRunning,
+			MaxQueued:      ec.MaxQueued,
+			MaxRetries:     ec.MaxRetries,
+			RetryBackoff:   backoff,
+			RemoveWhenDone: ec.RemoveWhenDone,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cron: entry %q: %v", ec.Name, err)
+		}
+	}
+	return c, nil
+}
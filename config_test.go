@@ -0,0 +1,117 @@
+package cron
+
+import (
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigRoundTrip(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("Asia/Tokyo not available:", err)
+	}
+
+	cron := NewWithLocation(loc)
+	cron.AddFuncWithDesc("export", "nightly export", "0 0 2 * * ?", func() {})
+	cron.NameAndDelaySchedule("poll", Every(5*time.Minute), 0, FuncJob(func() {}))
+	cron.AddFuncSkipIfRunning("sync", "0 0 * * * ?", func() {})
+	cron.AddFuncQueueIfRunning("queue", "0 0 * * * ?", 3, func() {})
+	cron.Group("reports").AddNameFunc("weekly", "0 0 9 * * 1", func() {})
+	cron.Group("reports").PauseAll()
+	cron.SetRunInline("poll", true)
+	cron.AddFuncInLocation("utc", "0 0 0 * * ?", time.UTC, func() {})
+	cron.SetRetries("sync", 3, 1500*time.Millisecond)
+	cron.SetRemoveWhenDone("export", true)
+
+	data, err := cron.MarshalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resolved []string
+	loaded, err := LoadConfig(data, func(name string) Job {
+		resolved = append(resolved, name)
+		return FuncJob(func() {})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Location().String() != "Asia/Tokyo" {
+		t.Errorf("expected location Asia/Tokyo, got %v", loaded.Location())
+	}
+	if want := []string{"export", "poll", "sync", "queue", "weekly", "utc"}; !reflect.DeepEqual(resolved, want) {
+		t.Errorf("expected jobs resolved in the order added %v, got %v", want, resolved)
+	}
+	if e, _ := loaded.GetEntry("weekly"); e.Group != "reports" || !e.Paused {
+		t.Errorf("expected the group and paused state to round-trip, got %+v", e)
+	}
+	if e, _ := loaded.GetEntry("sync"); e.MaxRetries != 3 || e.RetryBackoff != 1500*time.Millisecond {
+		t.Errorf("expected the retries to round-trip, got %+v", e)
+	}
+	if e, _ := loaded.GetEntry("queue"); !e.QueueIfRunning || e.MaxQueued != 3 {
+		t.Errorf("expected the queue policy to round-trip, got %+v", e)
+	}
+	if e, _ := loaded.GetEntry("export"); !e.RemoveWhenDone {
+		t.Errorf("expected RemoveWhenDone to round-trip, got %+v", e)
+	}
+	if e, _ := loaded.GetEntry("utc"); e.Location == nil || e.Location.String() != "UTC" {
+		t.Errorf("expected the entry location to round-trip, got %+v", e)
+	}
+	again, err := loaded.MarshalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("expected the config to round-trip:\n%s\n%s", data, again)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	var buf syncWriter
+	cron := New()
+	cron.ErrorLog = log.New(&buf, "", 0)
+	cron.AddNameFunc("hourly", "@hourly", func() {})
+	cron.NameAndDelaySchedule("custom", &ZeroSchedule{}, 0, FuncJob(func() {}))
+	cron.AddOnceFunc("once", time.Now().Add(time.Hour), func() {})
+	data, err := cron.MarshalConfig()
+	if err != nil {
+		t.Errorf("expected entries without a spec to be left out, got %v", err)
+	}
+	loaded, err := LoadConfig(data, func(string) Job { return FuncJob(func() {}) })
+	if err != nil || loaded.EntryCount() != 1 {
+		t.Errorf("expected only the entry with a spec to be exported, got %s", data)
+	}
+	if out := buf.String(); !strings.Contains(out, `"custom"`) || !strings.Contains(out, `"once"`) {
+		t.Errorf("expected each entry left out to be logged, got %q", out)
+	}
+	cron = New()
+	cron.AddFunc("@hourly", func() {})
+	if _, err := cron.MarshalConfig(); err == nil {
+		t.Error("expected an error for an entry without a name")
+	}
+
+	noJob := func(string) Job { return nil }
+	for _, data := range []string{
+		`not json`,
+		`{"location":"Nowhere/Atlantis","entries":[]}`,
+		`{"location":"UTC","entries":[{"name":"a","spec":"bad"}]}`,
+		`{"location":"UTC","entries":[{"name":"a","spec":"@hourly"}]}`,
+		`{"location":"UTC","entries":[{"spec":"@hourly"}]}`,
+	} {
+		if _, err := LoadConfig([]byte(data), noJob); err == nil {
+			t.Errorf("expected an error loading %s", data)
+		}
+	}
+
+	c, err := LoadConfig([]byte(`{"location":"UTC","entries":[{"name":"a","spec":"@hourly","delayRange":5}]}`),
+		func(string) Job { return FuncJob(func() {}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := c.entries[0]; e.Name != "a" || e.Spec != "@hourly" || e.DelayRange != 5 {
+		t.Errorf("unexpected entry %+v", e)
+	}
+}
@@ -20,6 +20,11 @@ func Every(duration time.Duration) ConstantDelaySchedule {
 	}
 }
 
+// String returns the schedule as an "@every" spec, e.g. "@every 1h30m0s".
+func (schedule ConstantDelaySchedule) String() string {
+	return "@every " + schedule.Delay.String()
+}
+
 // Next returns the next time this should be run.
 // This rounds so that the next activation time will be on the second.
 func (schedule ConstantDelaySchedule) Next(t time.Time) time.Time {
@@ -29,3 +34,8 @@ func (schedule ConstantDelaySchedule) Next(t time.Time) time.Time {
 func (schedule ConstantDelaySchedule) RandomNext(t time.Time, delayRange int) time.Time {
 	return t.Add(schedule.Delay - time.Duration(t.Nanosecond())*time.Nanosecond)
 }
+
+// randomNext is RandomNext, which takes no delay, for randomNextFrom.
+func (schedule ConstantDelaySchedule) randomNext(t time.Time, delayRange int, intn func(n int64) int64) time.Time {
+	return schedule.RandomNext(t, delayRange)
+}
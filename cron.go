@@ -1,10 +1,16 @@
 package cron
 
 import (
+	"container/heap"
+	"context"
 	"errors"
+	"fmt"
 	"log"
+	"math/rand"
 	"runtime"
 	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,12 +20,56 @@ import (
 type Cron struct {
 	entries  []*Entry
 	stop     chan struct{}
-	add      chan *Entry
-	remove   chan string
-	snapshot chan []*Entry
+	stopped  chan struct{}
+	ops      chan func()
+	snapshot chan snapshot
+	mu       sync.Mutex // guards running and requests to the scheduler goroutine
 	running  bool
 	ErrorLog *log.Logger
 	location *time.Location
+	shared   *SharedScheduler
+	slot     int // index among the started owners of the shared scheduler
+	unknown  func(op, name string)
+	dispatch func(job func())
+	sem      chan struct{} // slots of the jobs allowed to run at once, or nil
+	shutdown []*Entry
+	retries  retryQueue // retries of failed runs, soonest first
+	stack    int32      // panic stack trace buffer size, accessed atomically
+	active   int32      // number of jobs running, accessed atomically
+	runs     int64      // number of runs started, accessed atomically
+	minGap   int64      // minimum time between runs of an entry, accessed atomically
+	maxDelay int64      // maximum delay range of an entry in seconds, accessed atomically
+	added    int64      // number of entries added, accessed atomically
+	jobs     sync.WaitGroup
+	stats    *time.Ticker
+	ticked   time.Time // time of the last Tick, zero unless driven by Tick
+	statsFn  func(Stats)
+	early    time.Duration
+	dryRun   bool
+	spread   map[string]time.Duration // minimum gap between entries, by group
+	clock    Clock
+	rand     *rand.Rand
+	ctxMu    sync.Mutex // guards ctx and cancel
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	// OnEmpty, if non-nil, is called in its own goroutine whenever the last
+	// remaining entry is removed and the Cron becomes empty.
+	OnEmpty func()
+
+	// OnEntryDone, if non-nil, is called in its own goroutine with the name
+	// of each entry removed because its schedule has no activation left; see
+	// Entry.RemoveWhenDone.
+	OnEntryDone func(name string)
+
+	// OnJobStart and OnJobComplete, if non-nil, are called around every run
+	// of a job, with the name of its entry and the time the run started.
+	// OnJobComplete also gets how long the run took and, if the job panicked,
+	// the value recovered and the stack trace of the panic, or nil. Both are called on the goroutine running
+	// the job, so they must be safe for concurrent use, and must be set
+	// before the Cron is started.
+	OnJobStart    func(name string, t time.Time)
+	OnJobComplete func(name string, t time.Time, dur time.Duration, recovered interface{}, stack []byte)
 }
 
 // Job is an interface for submitted cron jobs.
@@ -45,6 +95,10 @@ type Entry struct {
 	// started or this entry's schedule is unsatisfiable
 	Next time.Time
 
+	// The next activation time according to the schedule, before the random
+	// delay is applied. Next minus BaseNext is the delay chosen for the run.
+	BaseNext time.Time
+
 	// The last time this job was run. This is the zero time if the job has never
 	// been run.
 	Prev time.Time
@@ -55,27 +109,169 @@ type Entry struct {
 	// Unique name to identify the Entry so as to be able to remove it later.
 	Name string
 
+	// Optional human-readable description of what the job does.
+	Description string
+
+	// The spec the schedule was parsed from, or empty if the entry was added
+	// with a Schedule directly.
+	Spec string
+
 	// 随机延迟的范围,以DelayRange为最大范围生成一个随机数R，让下一次执行延迟R秒，单位 秒 ，范围 (0,DelayRange)
 	DelayRange int
+
+	// The number of times the job was started.
+	RunCount int
+
+	// The number of activations on which the job was due but not run, and
+	// the same count broken down by the reason the run was suppressed.
+	SkipCount   int
+	SkipReasons map[string]int
+
+	// RunIf, if non-nil, is evaluated just before each run, and the run is
+	// skipped when it returns false. It is called on the scheduler goroutine,
+	// so it must be quick and must not call back into the Cron.
+	RunIf func() bool
+
+	// RunInline runs the job synchronously on the scheduler goroutine, still
+	// with panic recovery, instead of in a goroutine of its own. This saves
+	// the goroutine for trivial, high-frequency jobs, but an inline job delays
+	// every other entry while it runs, so it must be fast; runs that take
+	// longer than 50ms are logged.
+	RunInline bool
+
+	// SkipIfRunning skips an activation, counting it with the reason
+	// "running", while a previous run of the job is still in progress, so
+	// that runs of a job that overruns its interval never overlap.
+	SkipIfRunning bool
+
+	// QueueIfRunning holds back an activation that fires while a previous run
+	// of the job is in progress, and starts it as soon as that run returns,
+	// so that runs never overlap and no activation is lost. A job that is
+	// consistently slower than its schedule would queue runs without bound,
+	// so at most MaxQueued activations wait at a time, or 16 if MaxQueued is
+	// zero; further ones are skipped with the reason "queue full" and logged.
+	QueueIfRunning bool
+	MaxQueued      int
+
+	// The group the entry was added to through a Group, or empty.
+	Group string
+
+	// Paused entries keep their schedule, but their activations are skipped,
+	// counted with the reason "paused", until they are resumed.
+	Paused bool
+
+	// MaxRetries is the number of times a run of an ErrorJob that returns an
+	// error is retried, after RetryBackoff, then twice as long and so on,
+	// before it is given up. Retries wait in a queue of their own, see
+	// PendingRetries, so they keep no goroutine and do not delay the other
+	// entries, and are abandoned when the Cron is stopped. Inline entries
+	// are not retried.
+	MaxRetries   int
+	RetryBackoff time.Duration
+
+	// RemoveWhenDone removes the entry once its schedule has no activation
+	// left after a run, as with At or a schedule that has expired, instead of
+	// keeping it with a zero Next.
+	RemoveWhenDone bool
+
+	// The location the entry's schedule is computed in, if it was added with
+	// one of its own through AddFuncInLocation, InLocation or a CRON_TZ= spec,
+	// or nil if it follows the location of the Cron.
+	Location *time.Location
+
+	// The number of runs of the job in progress, accessed atomically.
+	running int32
+
+	// The activations waiting for the run in progress, for QueueIfRunning.
+	queued *runQueue
+
+	// The order in which the entry was added, among the entries of its Cron.
+	seq int64
+}
+
+// String returns a one-line summary of the entry, suitable for logs and
+// dashboards. A paused entry still shows its next activation, followed by
+// "(paused)" as it will not run then.
+func (e *Entry) String() string {
+	s := e.Name
+	if s == "" {
+		s = "(unnamed)"
+	}
+	if e.Description != "" {
+		s += " (" + e.Description + ")"
+	}
+	if e.Next.IsZero() {
+		s += " next: never"
+	} else {
+		s += " next: " + e.Next.Format(time.RFC3339)
+	}
+	if e.Paused {
+		s += " (paused)"
+	}
+	return s
+}
+
+// SpecString returns the spec of the entry's schedule. It is the spec the
+// entry was added with if there is one, or otherwise the canonical form of a
+// schedule that describes itself through a String method that Parse accepts,
+// such as the schedule returned by Every. ok is false, and the spec empty,
+// when the schedule cannot be represented as a spec, e.g. for At, Union or
+// custom Schedule types.
+func (e *Entry) SpecString() (spec string, ok bool) {
+	if e.Spec != "" {
+		return e.Spec, true
+	}
+	if s, isStringer := e.Schedule.(fmt.Stringer); isStringer {
+		if _, err := Parse(s.String()); err == nil {
+			return s.String(), true
+		}
+	}
+	return "", false
+}
+
+// scheduleString returns the String of s, or "<custom>" if it has none.
+func scheduleString(s Schedule) string {
+	if s, ok := s.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return "<custom>"
+}
+
+// SkippedRuns returns the number of activations on which the job was due but
+// its run was suppressed. A high count for a job usually means it overruns or
+// is otherwise prevented from keeping up with its schedule.
+func (e *Entry) SkippedRuns() int {
+	return e.SkipCount
 }
 
 // byTime is a wrapper for sorting the entry array by time
-// (with zero time at the end).
+// (with zero time at the end). It also implements heap.Interface, which is
+// how the scheduler keeps its entries ordered while running.
 type byTime []*Entry
 
 func (s byTime) Len() int      { return len(s) }
 func (s byTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 func (s byTime) Less(i, j int) bool {
-	// Two zero times should return false.
-	// Otherwise, zero is "greater" than any other time.
+	// Zero is "greater" than any other time.
 	// (To sort it at the end of the list.)
-	if s[i].Next.IsZero() {
-		return false
+	// Equal times, zero or not, keep the order the entries were added in.
+	if s[i].Next.IsZero() != s[j].Next.IsZero() {
+		return s[j].Next.IsZero()
 	}
-	if s[j].Next.IsZero() {
-		return true
+	if !s[i].Next.Equal(s[j].Next) {
+		return s[i].Next.Before(s[j].Next)
 	}
-	return s[i].Next.Before(s[j].Next)
+	return s[i].seq < s[j].seq
+}
+
+func (s *byTime) Push(x interface{}) { *s = append(*s, x.(*Entry)) }
+func (s *byTime) Pop() interface{} {
+	old := *s
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil // release the entry; the backing array outlives it
+	*s = old[:n-1]
+	return e
 }
 
 // New returns a new Cron job runner, in the Local time zone.
@@ -83,17 +279,21 @@ func New() *Cron {
 	return NewWithLocation(time.Now().Location())
 }
 
-// NewWithLocation returns a new Cron job runner.
+// NewWithLocation returns a new Cron job runner. A nil location means UTC.
 func NewWithLocation(location *time.Location) *Cron {
+	if location == nil {
+		location = time.UTC
+	}
 	return &Cron{
 		entries:  nil,
-		add:      make(chan *Entry),
-		remove:   make(chan string),
+		ops:      make(chan func()),
 		stop:     make(chan struct{}),
-		snapshot: make(chan []*Entry),
+		stopped:  make(chan struct{}),
+		snapshot: make(chan snapshot),
 		running:  false,
 		ErrorLog: nil,
 		location: location,
+		maxDelay: DefaultMaxDelay,
 	}
 }
 
@@ -102,6 +302,69 @@ type FuncJob func()
 
 func (f FuncJob) Run() { f() }
 
+// ErrorJob is a job that reports failure by returning an error. Errors
+// returned by jobs added with AddErrorJob, AddErrorFunc or their named
+// variants are logged through ErrorLog, along with the entry name.
+type ErrorJob interface {
+	Run() error
+}
+
+// ErrorFuncJob is a wrapper that turns a func() error into a cron.ErrorJob.
+type ErrorFuncJob func() error
+
+func (f ErrorFuncJob) Run() error { return f() }
+
+// errorJob adapts an ErrorJob to the Job interface. Run discards the error;
+// runWithRecovery unwraps it to log the error instead.
+type errorJob struct {
+	job ErrorJob
+}
+
+func (j errorJob) Run() { j.job.Run() }
+
+// JobWithContext is a job that is given a context, which is cancelled when
+// the Cron is stopped so that long-running jobs can return early.
+type JobWithContext interface {
+	Run(ctx context.Context)
+}
+
+// ContextFuncJob is a wrapper that turns a func(context.Context) into a
+// cron.JobWithContext.
+type ContextFuncJob func(ctx context.Context)
+
+func (f ContextFuncJob) Run(ctx context.Context) { f(ctx) }
+
+// contextJob adapts a JobWithContext to the Job interface. Run passes a
+// context that is never cancelled; runWithRecovery unwraps it to pass the
+// context of the Cron instead.
+type contextJob struct {
+	job JobWithContext
+}
+
+func (j contextJob) Run() { j.job.Run(context.Background()) }
+
+// jobContext returns the context given to the jobs that are started until
+// the Cron is next stopped.
+func (c *Cron) jobContext() context.Context {
+	c.ctxMu.Lock()
+	defer c.ctxMu.Unlock()
+	if c.ctx == nil {
+		c.ctx, c.cancel = context.WithCancel(context.Background())
+	}
+	return c.ctx
+}
+
+// cancelJobs cancels the context of the jobs started so far. Jobs started
+// later get a new one.
+func (c *Cron) cancelJobs() {
+	c.ctxMu.Lock()
+	defer c.ctxMu.Unlock()
+	if c.cancel != nil {
+		c.cancel()
+		c.ctx, c.cancel = nil, nil
+	}
+}
+
 // AddFunc adds a func to the Cron to be run on the given schedule.
 func (c *Cron) AddNameFunc(name string, spec string, cmd func()) error {
 	return c.AddNameJob(name, spec, FuncJob(cmd))
@@ -109,10 +372,41 @@ func (c *Cron) AddNameFunc(name string, spec string, cmd func()) error {
 func (c *Cron) AddFunc(spec string, cmd func()) error {
 	return c.AddJob(spec, FuncJob(cmd))
 }
+
+// AddContextFunc adds a named func to the Cron that is given a context, which
+// is cancelled when the Cron is stopped; see JobWithContext.
+func (c *Cron) AddContextFunc(name, spec string, cmd func(ctx context.Context)) error {
+	return c.AddContextJob(name, spec, ContextFuncJob(cmd))
+}
+
+// AddContextJob is like AddContextFunc, for a JobWithContext.
+func (c *Cron) AddContextJob(name, spec string, cmd JobWithContext) error {
+	return c.AddNameJob(name, spec, contextJob{cmd})
+}
+
+// AddErrorFunc adds a func returning an error to the Cron to be run on the
+// given schedule. A non-nil error returned by a run is logged.
+func (c *Cron) AddErrorFunc(spec string, cmd func() error) error {
+	return c.AddNameErrorFunc("", spec, cmd)
+}
+
+// AddNameErrorFunc is like AddErrorFunc, with a name identifying the entry.
+func (c *Cron) AddNameErrorFunc(name string, spec string, cmd func() error) error {
+	return c.AddNameErrorJob(name, spec, ErrorFuncJob(cmd))
+}
+
+// AddErrorJob adds an ErrorJob to the Cron to be run on the given schedule.
+// A non-nil error returned by a run is logged.
+func (c *Cron) AddErrorJob(spec string, cmd ErrorJob) error {
+	return c.AddNameErrorJob("", spec, cmd)
+}
+
+// AddNameErrorJob is like AddErrorJob, with a name identifying the entry.
+func (c *Cron) AddNameErrorJob(name string, spec string, cmd ErrorJob) error {
+	return c.AddNameJob(name, spec, errorJob{cmd})
+}
+
 func (c *Cron) AddDelayFunc(spec string, delayRange int, cmd func()) error {
-	if delayRange < 0 || delayRange > 82800 {
-		return errors.New("delayRange cannot exceed 0-82800 second.（24H）")
-	}
 	return c.AddDelayJob(spec, delayRange, FuncJob(cmd))
 }
 
@@ -126,44 +420,618 @@ func (c *Cron) AddNameJob(name string, spec string, cmd Job) error {
 	if err != nil {
 		return err
 	}
-	c.NameAndDelaySchedule(name, schedule, 0, cmd)
-	return nil
+	return c.addEntry(&Entry{
+		Schedule: schedule,
+		Job:      cmd,
+		Name:     name,
+		Spec:     spec,
+	})
 }
 
 func (c *Cron) AddDelayJob(spec string, delayRange int, cmd Job) error {
-	if delayRange < 0 || delayRange > 82800 {
-		return errors.New("delayRange cannot exceed 0-82800 second.（24H）")
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
 	}
+	return c.addEntry(&Entry{
+		Schedule:   schedule,
+		Job:        cmd,
+		DelayRange: delayRange,
+		Spec:       spec,
+	})
+}
+
+// AddFuncWithParser adds a func to the Cron, parsing spec with p instead of
+// the default parser. This allows specs in different dialects to be mixed in
+// a single Cron.
+func (c *Cron) AddFuncWithParser(name, spec string, p Parser, cmd func()) error {
+	return c.AddJobWithParser(name, spec, p, FuncJob(cmd))
+}
+
+// AddFuncWithDesc adds a named func to the Cron along with a human-readable
+// description, which is reported in snapshots and by Entry.String.
+func (c *Cron) AddFuncWithDesc(name, desc, spec string, cmd func()) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	return c.addEntry(&Entry{
+		Schedule:    schedule,
+		Job:         FuncJob(cmd),
+		Name:        name,
+		Description: desc,
+		Spec:        spec,
+	})
+}
+
+// AddFuncSkipIfRunning adds a named func to the Cron whose activations are
+// skipped while its previous run is still in progress; see
+// Entry.SkipIfRunning.
+func (c *Cron) AddFuncSkipIfRunning(name, spec string, cmd func()) error {
 	schedule, err := Parse(spec)
 	if err != nil {
 		return err
 	}
-	c.NameAndDelaySchedule("", schedule, delayRange, cmd)
+	return c.addEntry(&Entry{
+		Schedule:      schedule,
+		Job:           FuncJob(cmd),
+		Name:          name,
+		Spec:          spec,
+		SkipIfRunning: true,
+	})
+}
+
+// AddFuncQueueIfRunning adds a named func to the Cron whose activations wait
+// for its previous run to return instead of overlapping it, with at most
+// maxQueued of them waiting at a time; see Entry.QueueIfRunning.
+func (c *Cron) AddFuncQueueIfRunning(name, spec string, maxQueued int, cmd func()) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	return c.addEntry(&Entry{
+		Schedule:       schedule,
+		Job:            FuncJob(cmd),
+		Name:           name,
+		Spec:           spec,
+		QueueIfRunning: true,
+		MaxQueued:      maxQueued,
+	})
+}
+
+// AddFuncDisabled adds a named func to the Cron that is paused from the
+// start: it shows up in snapshots, but never runs until it is resumed with
+// Resume.
+func (c *Cron) AddFuncDisabled(name, spec string, cmd func()) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	return c.addEntry(&Entry{
+		Schedule: schedule,
+		Job:      FuncJob(cmd),
+		Name:     name,
+		Spec:     spec,
+		Paused:   true,
+	})
+}
+
+// AddFuncInLocation adds a named func to the Cron whose schedule is computed
+// in loc instead of the location of the Cron; see InLocation. It returns an
+// error if loc is nil.
+func (c *Cron) AddFuncInLocation(name, spec string, loc *time.Location, cmd func()) error {
+	if loc == nil {
+		return fmt.Errorf("cron: no location for %q", name)
+	}
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	return c.addEntry(&Entry{
+		Schedule: InLocation(schedule, loc),
+		Job:      FuncJob(cmd),
+		Name:     name,
+		Spec:     spec,
+		Location: loc,
+	})
+}
+
+// AddOnceFunc adds a func to the Cron to be run once, at the given time, with
+// the At schedule. The entry is kept after its run, with a zero Next, unless
+// it is set to be removed with SetRemoveWhenDone. It returns an error if at is
+// not in the future.
+func (c *Cron) AddOnceFunc(name string, at time.Time, cmd func()) error {
+	if !at.After(c.now()) {
+		return fmt.Errorf("cron: time of %q (%v) is not in the future", name, at)
+	}
+	return c.addEntry(&Entry{
+		Schedule: At(at),
+		Job:      FuncJob(cmd),
+		Name:     name,
+	})
+}
+
+// AddJobWithParser adds a Job to the Cron, parsing spec with p instead of the
+// default parser.
+func (c *Cron) AddJobWithParser(name, spec string, p Parser, cmd Job) error {
+	schedule, err := p.Parse(spec)
+	if err != nil {
+		return err
+	}
+	return c.addEntry(&Entry{
+		Schedule: schedule,
+		Job:      cmd,
+		Name:     name,
+		Spec:     spec,
+	})
+}
+
+// Submit runs cmd once, as soon as possible, the way the scheduler runs due
+// jobs: through the dispatcher, with panic recovery, and counted in Stats and
+// ActiveCount. It is never added to the entries, so there is nothing to
+// remove afterwards. The name only serves to identify the task.
+func (c *Cron) Submit(name string, cmd func()) {
+	c.do(func() {
+		atomic.AddInt64(&c.runs, 1)
+		c.startJob(&Entry{Name: name, Job: FuncJob(cmd)})
+	})
+}
+
+// TriggerNow runs the job of the named entry once, right away, the way Submit
+// runs a task, or returns an error if there is no such entry. The entry's
+// schedule, Prev, Next and counts are left as they are.
+func (c *Cron) TriggerNow(name string) error {
+	found := false
+	c.do(func() {
+		if i := pos(c.entries, name); name != "" && i != -1 {
+			found = true
+			atomic.AddInt64(&c.runs, 1)
+			c.startJob(&Entry{Name: name, Job: c.entries[i].Job})
+		}
+	})
+	if !found {
+		c.unknownName("trigger", name)
+		return errNoEntry(name)
+	}
 	return nil
 }
 
-// RemoveJob removes a Job from the Cron based on name.
-func (c *Cron) RemoveJob(name string) {
-	if c.running {
-		c.remove <- name
-		return
+// AddShutdownJob registers cmd to be run once when the scheduler stops, rather
+// than on a schedule. Shutdown jobs run in registration order, one after the
+// other, before Stop and Run return; with StopAndWait, only once the running
+// jobs have returned or the timeout has elapsed. The name only serves to
+// identify the job.
+// Shutdown jobs run while the scheduler is stopping, so they must not call
+// back into the Cron.
+func (c *Cron) AddShutdownJob(name string, cmd func()) {
+	c.do(func() {
+		c.shutdown = append(c.shutdown, &Entry{Name: name, Job: FuncJob(cmd)})
+	})
+}
+
+// runShutdownJobs runs the given shutdown jobs, in order.
+func (c *Cron) runShutdownJobs(shutdown []*Entry) {
+	for _, e := range shutdown {
+		c.runWithRecovery(c.jobContext(), e.Name, e.Job)
 	}
+}
 
-	i := pos(c.entries, name)
-	if i == -1 {
+// RemoveJob removes a Job from the Cron based on name, and reports whether
+// there was an entry with that name to remove.
+func (c *Cron) RemoveJob(name string) bool {
+	found := false
+	c.do(func() {
+		if i := pos(c.entries, name); i != -1 {
+			c.removeAt(i)
+			found = true
+		}
+	})
+	if !found {
+		c.unknownName("remove", name)
+	}
+	return found
+}
+
+// SetUnknownNameHandler registers fn to be called when a name-based operation
+// such as RemoveJob refers to a name that no entry has. The operation is
+// reported as op: "remove", "condition", "inline", "trigger", "pause" or
+// "resume". A nil fn restores the default of silently ignoring unknown names.
+func (c *Cron) SetUnknownNameHandler(fn func(op, name string)) {
+	c.do(func() { c.unknown = fn })
+}
+
+// SetRunIf sets the condition under which the named entry runs; see
+// Entry.RunIf. A nil fn removes the condition.
+func (c *Cron) SetRunIf(name string, fn func() bool) {
+	found := false
+	c.do(func() {
+		if i := pos(c.entries, name); i != -1 {
+			c.entries[i].RunIf = fn
+			found = true
+		}
+	})
+	if !found {
+		c.unknownName("condition", name)
+	}
+}
+
+// UpdateSchedule replaces the schedule of the named entry with the one parsed
+// from spec, in place, keeping its job, Prev, counts and other settings. An
+// entry with a location of its own keeps it unless spec has a CRON_TZ= prefix.
+// Once the Cron is running, the next activation is recomputed from now. It
+// returns an error if spec is invalid, fires more often than the minimum
+// interval or if there is no entry with the name.
+func (c *Cron) UpdateSchedule(name, spec string) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	if err := c.checkInterval(schedule); err != nil {
+		return err
+	}
+	found := false
+	c.do(func() {
+		i := pos(c.entries, name)
+		if i == -1 {
+			return
+		}
+		found = true
+		e := c.entries[i]
+		if loc, ok := scheduleLocation(schedule); ok {
+			e.Location = loc
+		} else if e.Location != nil {
+			schedule = InLocation(schedule, e.Location)
+		}
+		e.Schedule, e.Spec = schedule, spec
+		switch {
+		case c.running:
+			c.scheduleNext(e, c.now())
+		case !c.ticked.IsZero():
+			c.scheduleNext(e, c.ticked)
+		default:
+			return
+		}
+		heap.Fix(c.queue(), i)
+	})
+	if !found {
+		return errNoEntry(name)
+	}
+	return nil
+}
+
+// SetRunInline sets whether the named entry runs inline on the scheduler
+// goroutine; see Entry.RunInline.
+func (c *Cron) SetRunInline(name string, inline bool) {
+	found := false
+	c.do(func() {
+		if i := pos(c.entries, name); i != -1 {
+			c.entries[i].RunInline = inline
+			found = true
+		}
+	})
+	if !found {
+		c.unknownName("inline", name)
+	}
+}
+
+// PauseWhere pauses every entry for which match returns true, in a single
+// step with respect to the scheduler, and returns how many entries matched;
+// see Entry.Paused. match is called on the scheduler goroutine with the
+// entries themselves, so it must be quick, must not modify them and must not
+// call back into the Cron.
+func (c *Cron) PauseWhere(match func(*Entry) bool) int {
+	return c.setPausedWhere(match, true)
+}
+
+// ResumeWhere resumes every entry for which match returns true, as PauseWhere
+// pauses them, and returns how many entries matched.
+func (c *Cron) ResumeWhere(match func(*Entry) bool) int {
+	return c.setPausedWhere(match, false)
+}
+
+// Pause pauses the named entry, see Entry.Paused, or returns an error if
+// there is no such entry. Its history is kept and its Next still advances, so
+// that it picks up its schedule where it is once resumed.
+func (c *Cron) Pause(name string) error {
+	return c.setPaused("pause", name, true)
+}
+
+// Resume resumes the named entry, or returns an error if there is no such
+// entry.
+func (c *Cron) Resume(name string) error {
+	return c.setPaused("resume", name, false)
+}
+
+// setPaused implements Pause and Resume, reporting an unknown name as op.
+func (c *Cron) setPaused(op, name string, paused bool) error {
+	if name == "" || c.setPausedWhere(func(e *Entry) bool { return e.Name == name }, paused) == 0 {
+		c.unknownName(op, name)
+		return errNoEntry(name)
+	}
+	return nil
+}
+
+// errNoEntry returns the error of an operation on a missing name.
+func errNoEntry(name string) error {
+	return fmt.Errorf("cron: no entry named %q", name)
+}
+
+func (c *Cron) setPausedWhere(match func(*Entry) bool, paused bool) int {
+	n := 0
+	c.do(func() {
+		for _, e := range c.entries {
+			if match(e) {
+				e.Paused = paused
+				n++
+			}
+		}
+	})
+	return n
+}
+
+// unknownName reports an operation on a missing name to the handler, if any.
+// It must be called outside of do, as the handler may call back into c.
+func (c *Cron) unknownName(op, name string) {
+	var handler func(op, name string)
+	c.do(func() { handler = c.unknown })
+	if handler != nil {
+		handler(op, name)
+	}
+}
+
+// SetDispatcher overrides how jobs are launched: when an entry is due, the
+// scheduler calls fn with a closure that runs the job, with panic recovery,
+// instead of starting a goroutine for it. This allows jobs to be routed into
+// an existing worker pool. fn is called on the scheduler goroutine, so it
+// should hand the closure off rather than run it. A nil fn restores the
+// default of one goroutine per run.
+func (c *Cron) SetDispatcher(fn func(job func())) {
+	c.do(func() { c.dispatch = fn })
+}
+
+// SetEarlyFireTolerance lets entries that are due within d of a run also run
+// with it, instead of waking the scheduler again shortly after. This reduces
+// wakeups, e.g. on battery-powered devices, at the cost of jobs running up to
+// d early. The default of zero runs every job at its exact time.
+func (c *Cron) SetEarlyFireTolerance(d time.Duration) {
+	c.do(func() { c.early = d })
+}
+
+// SetDryRun enables or disables dry-run mode. While enabled, the scheduler
+// keeps computing activations and advancing Prev and Next as usual, and logs
+// each run it would perform, but does not run the jobs. This allows checking
+// a new schedule against real time without side effects. Dry runs are not
+// counted in RunCount, nor in SkipCount.
+func (c *Cron) SetDryRun(enabled bool) {
+	c.do(func() { c.dryRun = enabled })
+}
+
+// inlineWarnThreshold is the duration beyond which an inline run is logged.
+const inlineWarnThreshold = 50 * time.Millisecond
+
+// runInline runs the entry's job on the calling goroutine, logging it if it
+// holds up the scheduler for too long.
+func (c *Cron) runInline(e *Entry) {
+	start := time.Now()
+	c.runWithRecovery(c.jobContext(), e.Name, e.Job)
+	if d := time.Since(start); d > inlineWarnThreshold {
+		c.logf("cron: inline job %q took %v, blocking the scheduler; inline jobs must be fast", e.Name, d)
+	}
+}
+
+// startJob launches the entry's job through the dispatcher, or in its own
+// goroutine. A run that fails is retried through the retry queue, with the
+// context of the jobs started now, so that stopping the Cron abandons the
+// retries still waiting.
+func (c *Cron) startJob(e *Entry) {
+	c.jobs.Add(1)
+	atomic.AddInt32(&e.running, 1)
+	ctx, name, j, q, sem := c.jobContext(), e.Name, e.Job, e.queued, c.sem
+	retries, backoff := e.MaxRetries, e.RetryBackoff
+	runOnce := func() {
+		if err := c.attempt(ctx, sem, name, j); err != nil && retries > 0 {
+			c.retryLater(&retry{ctx: ctx, name: name, job: j, max: retries, backoff: backoff})
+		}
+	}
+	run := func() {
+		defer c.jobs.Done()
+		defer atomic.AddInt32(&e.running, -1)
+		runOnce()
+		for q != nil && q.next() {
+			runOnce()
+		}
+	}
+	if c.dispatch != nil {
+		c.dispatch(run)
 		return
 	}
-	c.entries = removeEntry(c.entries, i)
+	go run()
+}
+
+// SetMaxConcurrent limits the number of jobs running at once to n. Jobs that
+// are started while n are running wait for one of them to return, in no
+// particular order, instead of being dropped. Inline jobs and shutdown jobs,
+// which run one at a time on the scheduler or stopping goroutine, are not
+// counted. The limit applies to jobs started from now on; n of zero or less,
+// the default, removes it.
+func (c *Cron) SetMaxConcurrent(n int) {
+	c.do(func() {
+		c.sem = nil
+		if n > 0 {
+			c.sem = make(chan struct{}, n)
+		}
+	})
+}
+
+// attempt runs a job once, holding one of the slots of sem, if non-nil, while
+// it runs, and returns the error of an ErrorJob.
+func (c *Cron) attempt(ctx context.Context, sem chan struct{}, name string, j Job) error {
+	if sem != nil {
+		sem <- struct{}{}
+		defer func() { <-sem }()
+	}
+	return c.runWithRecovery(ctx, name, j)
+}
+
+// SetRetries sets how many times runs of the named entry's ErrorJob that
+// fail are retried, and the backoff before the first retry; see
+// Entry.MaxRetries. It returns an error if there is no such entry.
+func (c *Cron) SetRetries(name string, maxRetries int, backoff time.Duration) error {
+	found := false
+	c.do(func() {
+		if i := pos(c.entries, name); name != "" && i != -1 {
+			c.entries[i].MaxRetries, c.entries[i].RetryBackoff = maxRetries, backoff
+			found = true
+		}
+	})
+	if !found {
+		return errNoEntry(name)
+	}
+	return nil
+}
+
+// SetRemoveWhenDone sets whether the named entry is removed once its schedule
+// has no activation left; see Entry.RemoveWhenDone. It returns an error if
+// there is no entry with that name.
+func (c *Cron) SetRemoveWhenDone(name string, remove bool) error {
+	found := false
+	c.do(func() {
+		if i := pos(c.entries, name); name != "" && i != -1 {
+			c.entries[i].RemoveWhenDone = remove
+			found = true
+		}
+	})
+	if !found {
+		return errNoEntry(name)
+	}
+	return nil
+}
+
+// defaultMaxQueued is the number of activations of a QueueIfRunning entry
+// that may wait at a time when MaxQueued is zero.
+const defaultMaxQueued = 16
+
+// runQueue counts the activations of a QueueIfRunning entry that wait for
+// the run in progress. It is shared by the scheduler, which queues
+// activations, and the goroutine running the job, which consumes them.
+type runQueue struct {
+	mu      sync.Mutex
+	busy    bool
+	pending int
+}
+
+// next consumes a waiting activation, or, if there is none, records that the
+// job is no longer running. It returns true if the job must run again.
+func (q *runQueue) next() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending > 0 {
+		q.pending--
+		return true
+	}
+	q.busy = false
+	return false
+}
+
+// queueRun returns true if the activation of the QueueIfRunning entry e can
+// start right away. Otherwise a run is in progress, and the activation is
+// queued behind it, or skipped if the queue is full.
+func (c *Cron) queueRun(e *Entry) bool {
+	if e.queued == nil {
+		e.queued = &runQueue{}
+	}
+	q := e.queued
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.busy {
+		q.busy = true
+		return true
+	}
+	max := e.MaxQueued
+	if max <= 0 {
+		max = defaultMaxQueued
+	}
+	if q.pending >= max {
+		c.skip(e, "queue full")
+		c.logf("cron: skipped %s, %d runs already queued", e, q.pending)
+		return false
+	}
+	q.pending++
+	e.RunCount++
+	atomic.AddInt64(&c.runs, 1)
+	return false
 }
 
-func removeEntry(entries []*Entry, index int) []*Entry {
-	target := entries[:0]
-	for i, v := range entries {
-		if i != index {
-			target = append(target, v)
+// do runs fn with exclusive access to the entries and returns once it has
+// completed: on the scheduler goroutine while running, under the shared
+// scheduler's lock for a shared Cron, and directly otherwise.
+func (c *Cron) do(fn func()) {
+	switch {
+	case c.shared != nil:
+		c.shared.update(c, fn)
+	default:
+		c.mu.Lock()
+		if !c.running {
+			defer c.mu.Unlock()
+			fn()
+			return
+		}
+		// The lock is held until the scheduler has taken the request, so
+		// that Stop cannot come in between, but not while it is handled,
+		// so that other requests can queue up and be drained with it.
+		done := make(chan struct{})
+		c.ops <- func() {
+			fn()
+			close(done)
 		}
+		c.mu.Unlock()
+		<-done
+	}
+}
+
+// minShrinkCap is the capacity below which the entries slice is never
+// reallocated to shrink it.
+const minShrinkCap = 64
+
+// removeAt removes the entry at index i, notifying OnEmpty if it was the last.
+func (c *Cron) removeAt(i int) {
+	heap.Remove(c.queue(), i)
+	c.removed()
+}
+
+// removeWhere removes every entry for which match returns true, notifying
+// OnEmpty if none is left, and returns how many were removed.
+func (c *Cron) removeWhere(match func(e *Entry) bool) int {
+	kept := c.entries[:0]
+	for _, e := range c.entries {
+		if !match(e) {
+			kept = append(kept, e)
+		}
+	}
+	n := len(c.entries) - len(kept)
+	if n == 0 {
+		return 0
+	}
+	for i := len(kept); i < len(c.entries); i++ {
+		c.entries[i] = nil // release the entry; the backing array outlives it
+	}
+	c.entries = kept
+	heap.Init(c.queue())
+	c.removed()
+	return n
+}
+
+// removed is called once entries have been removed. Once removals leave most
+// of the slice unused, the entries are copied to a smaller one so the backing
+// array does not stay at its peak size. If no entry is left, OnEmpty is
+// notified.
+func (c *Cron) removed() {
+	if n := len(c.entries); cap(c.entries) > minShrinkCap && n < cap(c.entries)/4 {
+		c.entries = append(make([]*Entry, 0, 2*n), c.entries...)
+	}
+	if len(c.entries) == 0 && c.OnEmpty != nil {
+		go c.OnEmpty()
 	}
-	return target
 }
 
 func pos(entrySlice []*Entry, name string) int {
@@ -175,46 +1043,317 @@ func pos(entrySlice []*Entry, name string) int {
 	return -1
 }
 
-// Schedule adds a Job to the Cron to be run on the given schedule.
+// Schedule adds a Job to the Cron to be run on the given schedule. If the
+// entry cannot be added, e.g. because it fires more often than the minimum
+// interval, the error is logged.
 func (c *Cron) Schedule(schedule Schedule, cmd Job) {
-	c.NameAndDelaySchedule("", schedule, 0, cmd)
+	if err := c.NameAndDelaySchedule("", schedule, 0, cmd); err != nil {
+		c.logf("cron: not adding %s: %v", scheduleString(schedule), err)
+	}
 }
 
-func (c *Cron) NameAndDelaySchedule(name string, schedule Schedule, delayRange int, cmd Job) {
-	if delayRange < 0 || delayRange > 82800 {
-		delayRange = 0
-	}
-	entry := &Entry{
+// NameAndDelaySchedule adds a named Job to the Cron to be run on the given
+// schedule, delayed by up to delayRange seconds. It returns an error, without
+// adding the entry, if delayRange is above the maximum, the schedule fires
+// too often, or another entry has the same name (ErrDuplicateName).
+func (c *Cron) NameAndDelaySchedule(name string, schedule Schedule, delayRange int, cmd Job) error {
+	return c.addEntry(&Entry{
 		Schedule:   schedule,
 		Job:        cmd,
 		Name:       name,
 		DelayRange: delayRange,
+	})
+}
+
+// addEntry adds a fully built entry, scheduling it if the Cron is running. It
+// returns an error if the delay range is out of bounds, see SetMaxDelay,
+// ErrTooFrequent if the schedule fires more often than the minimum interval,
+// and ErrDuplicateName if another entry has the same name, without
+// adding the entry.
+func (c *Cron) addEntry(entry *Entry) error {
+	if err := c.checkDelay(entry.DelayRange); err != nil {
+		return err
 	}
-	if !c.running {
-		c.entries = append(c.entries, entry)
-		return
+	if err := c.checkInterval(entry.Schedule); err != nil {
+		return err
+	}
+	entry.seq = atomic.AddInt64(&c.added, 1)
+	if loc, ok := scheduleLocation(entry.Schedule); ok && entry.Location == nil {
+		entry.Location = loc
+	}
+	var err error
+	c.do(func() {
+		if entry.Name != "" && pos(c.entries, entry.Name) != -1 {
+			err = ErrDuplicateName
+			return
+		}
+		switch {
+		case c.running:
+			c.scheduleNext(entry, c.now())
+		case !c.ticked.IsZero():
+			c.scheduleNext(entry, c.ticked)
+		}
+		heap.Push(c.queue(), entry)
+	})
+	return err
+}
+
+// ErrDuplicateName is returned when adding an entry with the name of an entry
+// the Cron already has.
+var ErrDuplicateName = errors.New("cron: an entry with that name already exists")
+
+// ErrTooFrequent is returned when adding an entry whose schedule fires more
+// often than the minimum interval set with SetMinInterval.
+var ErrTooFrequent = errors.New("cron: schedule fires more often than the minimum interval")
+
+// intervalSamples is the number of consecutive activations sampled to find the
+// smallest gap of a schedule.
+const intervalSamples = 10
+
+// SetMinInterval rejects entries added from now on whose schedule fires more
+// than once per d, so that the add returns ErrTooFrequent. The gap between
+// runs is measured on a sample of the schedule's upcoming activations, before
+// any random delay. A d of zero or less, the default, removes the limit.
+// Entries already added are kept.
+func (c *Cron) SetMinInterval(d time.Duration) {
+	atomic.StoreInt64(&c.minGap, int64(d))
+}
+
+// DefaultMaxDelay is the maximum delay range of an entry, in seconds, until it
+// is changed with SetMaxDelay.
+const DefaultMaxDelay = 86400
+
+// SetMaxDelay sets the maximum delay range, in seconds, of the entries added
+// from now on; adding an entry with a wider one returns an error. A maximum of
+// zero or less rejects any delay. Entries already added are kept.
+func (c *Cron) SetMaxDelay(seconds int) {
+	atomic.StoreInt64(&c.maxDelay, int64(seconds))
+}
+
+// checkDelay returns an error if delayRange is negative or above the maximum
+// delay range.
+func (c *Cron) checkDelay(delayRange int) error {
+	if max := atomic.LoadInt64(&c.maxDelay); delayRange < 0 || int64(delayRange) > max {
+		if max < 0 {
+			max = 0
+		}
+		return fmt.Errorf("cron: delay range of %d seconds out of bounds (0-%d seconds)", delayRange, max)
+	}
+	return nil
+}
+
+// checkInterval returns ErrTooFrequent if the schedule fires more often than
+// the minimum interval.
+func (c *Cron) checkInterval(schedule Schedule) error {
+	min := time.Duration(atomic.LoadInt64(&c.minGap))
+	if min <= 0 {
+		return nil
+	}
+	prev := schedule.Next(c.now())
+	for i := 0; i < intervalSamples && !prev.IsZero(); i++ {
+		next := schedule.Next(prev)
+		if !next.IsZero() && next.Sub(prev) < min {
+			return ErrTooFrequent
+		}
+		prev = next
 	}
+	return nil
+}
 
-	c.add <- entry
+// snapshot is a copy of the entries taken by the scheduler at time now.
+type snapshot struct {
+	entries []*Entry
+	now     time.Time
 }
 
 // Entries returns a snapshot of the cron entries.
 func (c *Cron) Entries() []*Entry {
+	entries, _ := c.SnapshotAt()
+	return entries
+}
+
+// EntrySummary describes an entry with plain values, so that it can be
+// marshaled to JSON, e.g. for a status endpoint; an Entry holds its Schedule
+// and Job as interfaces, which do not serialize.
+type EntrySummary struct {
+	Name       string    `json:"name"`
+	Schedule   string    `json:"schedule"`
+	Next       time.Time `json:"next"`
+	Prev       time.Time `json:"prev"`
+	DelayRange int       `json:"delayRange,omitempty"`
+}
+
+// EntriesJSON returns a summary of each entry, in the order of Entries. The
+// schedule of a summary is the String of the entry's schedule if it has one,
+// e.g. "0 30 9 * * 1-5" for a parsed spec or "at 2012-12-01T09:00:00Z" for At,
+// or otherwise the spec the entry was added with, or "<custom>" for a custom
+// Schedule type without either. The error is reserved for future use; it is
+// always nil.
+func (c *Cron) EntriesJSON() ([]EntrySummary, error) {
+	entries := c.Entries()
+	summaries := make([]EntrySummary, 0, len(entries))
+	for _, e := range entries {
+		schedule := scheduleString(e.Schedule)
+		if _, ok := e.Schedule.(fmt.Stringer); !ok && e.Spec != "" {
+			schedule = e.Spec
+		}
+		summaries = append(summaries, EntrySummary{
+			Name:       e.Name,
+			Schedule:   schedule,
+			Next:       e.Next,
+			Prev:       e.Prev,
+			DelayRange: e.DelayRange,
+		})
+	}
+	return summaries, nil
+}
+
+// EntriesForJob returns a snapshot of the entries whose job is j, compared as
+// interface values, e.g. every entry added with the same pointer to a struct
+// implementing Job. Jobs of types that cannot be compared, such as FuncJob,
+// are never equal, so it returns no entry for them.
+func (c *Cron) EntriesForJob(j Job) []*Entry {
+	var entries []*Entry
+	c.do(func() {
+		var matched []*Entry
+		for _, e := range c.entries {
+			if sameJob(e.Job, j) {
+				matched = append(matched, e)
+			}
+		}
+		sort.Slice(matched, func(i, k int) bool { return matched[i].seq < matched[k].seq })
+		for _, e := range matched {
+			entries = append(entries, copyEntry(e))
+		}
+	})
+	return entries
+}
+
+// sameJob returns true if a and b are equal, and false rather than panicking
+// as == does when they hold an uncomparable value such as a func.
+func sameJob(a, b Job) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
+
+// EntryCount returns the number of entries, without taking a snapshot of
+// them as Entries does.
+func (c *Cron) EntryCount() int {
+	n := 0
+	c.do(func() { n = len(c.entries) })
+	return n
+}
+
+// GetEntry returns a snapshot of the entry with the given name, and whether
+// there is one.
+func (c *Cron) GetEntry(name string) (entry *Entry, ok bool) {
+	c.do(func() {
+		if i := pos(c.entries, name); i != -1 {
+			entry, ok = copyEntry(c.entries[i]), true
+		}
+	})
+	return entry, ok
+}
+
+// NextN returns up to n upcoming activation times of the named entry, from
+// now, or nil if there is no such entry or its schedule is unsatisfiable. The
+// times follow Schedule.Next, so the actual runs may be delayed by up to the
+// entry's DelayRange.
+func (c *Cron) NextN(name string, n int) []time.Time {
+	var (
+		schedule Schedule
+		t        time.Time
+	)
+	c.do(func() {
+		if i := pos(c.entries, name); i != -1 {
+			schedule, t = c.entries[i].Schedule, c.now()
+		}
+	})
+	if schedule == nil {
+		return nil
+	}
+	var times []time.Time
+	for len(times) < n {
+		if t = schedule.Next(t); t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+	return times
+}
+
+// ResetStats zeroes the run and skip counts of the named entry, keeping its
+// schedule, Prev and Next, and reports whether there is such an entry.
+func (c *Cron) ResetStats(name string) bool {
+	found := false
+	c.do(func() {
+		if i := pos(c.entries, name); i != -1 {
+			e := c.entries[i]
+			e.RunCount, e.SkipCount, e.SkipReasons = 0, 0, nil
+			found = true
+		}
+	})
+	return found
+}
+
+// SnapshotAt returns a snapshot of the cron entries together with the
+// scheduler's current time, both taken at the same synchronized point. Use it
+// instead of Entries and time.Now to compute whether entries are overdue
+// consistently with the scheduler.
+func (c *Cron) SnapshotAt() (entries []*Entry, now time.Time) {
+	if c.shared != nil {
+		c.shared.mu.Lock()
+		defer c.shared.mu.Unlock()
+		return c.entrySnapshot(), c.now()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.running {
-		c.snapshot <- nil
+		c.snapshot <- snapshot{}
 		x := <-c.snapshot
-		return x
+		return x.entries, x.now
 	}
-	return c.entrySnapshot()
+	return c.entrySnapshot(), c.now()
 }
 
-// Location gets the time zone location
+// NextEntry returns the name and next activation time of the entry that runs
+// soonest. ok is false if no entry is scheduled to run, e.g. because the Cron
+// has not been started.
+func (c *Cron) NextEntry() (name string, next time.Time, ok bool) {
+	c.do(func() {
+		if len(c.entries) > 0 && !c.entries[0].Next.IsZero() {
+			name, next, ok = c.entries[0].Name, c.entries[0].Next, true
+		}
+	})
+	return name, next, ok
+}
+
+// ActiveCount returns the number of jobs that are running right now. It is
+// cheap to call and does not synchronize with the scheduler.
+func (c *Cron) ActiveCount() int {
+	return int(atomic.LoadInt32(&c.active))
+}
+
+// Location gets the time zone location. It is UTC if none was set.
 func (c *Cron) Location() *time.Location {
+	if c.location == nil {
+		return time.UTC
+	}
 	return c.location
 }
 
 // Start the cron scheduler in its own go-routine, or no-op if already started.
 func (c *Cron) Start() {
+	if c.shared != nil {
+		c.shared.start(c)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.running {
 		return
 	}
@@ -222,25 +1361,101 @@ func (c *Cron) Start() {
 	go c.run()
 }
 
+// Tick runs the entries that are due at now, as the scheduler goroutine does
+// when its timer fires. It lets a host that owns its own event loop drive the
+// Cron without a goroutine or timer: the first call schedules the entries
+// after now, and every later call runs those whose time has come. Entries
+// added after the first call are scheduled after the time of the last one.
+//
+// Tick is mutually exclusive with Start and Run, and is not synchronized: it
+// must be called from the goroutine that adds and removes entries.
+func (c *Cron) Tick(now time.Time) {
+	now = now.In(c.Location())
+	if c.ticked.IsZero() {
+		for _, e := range c.entries {
+			c.scheduleNext(e, now)
+		}
+		heap.Init(c.queue())
+	}
+	c.runDue(now)
+	c.ticked = now
+}
+
 // Run the cron scheduler, or no-op if already running.
+// A Cron obtained from a SharedScheduler has no goroutine of its own, so for
+// it Run does not block and is equivalent to Start.
 func (c *Cron) Run() {
+	if c.shared != nil {
+		c.shared.start(c)
+		return
+	}
+	c.mu.Lock()
 	if c.running {
+		c.mu.Unlock()
 		return
 	}
 	c.running = true
+	c.mu.Unlock()
 	c.run()
 }
 
-func (c *Cron) runWithRecovery(j Job) {
+// Bounds of the panic stack trace buffer size.
+const (
+	defaultPanicStackSize = 64 << 10
+	minPanicStackSize     = 1 << 10
+)
+
+// SetPanicStackSize sets the size in bytes of the buffer used to capture the
+// stack trace logged when a job panics. Deeper stacks are truncated to it. The
+// default is 64KB; sizes below 1KB are raised to 1KB.
+func (c *Cron) SetPanicStackSize(bytes int) {
+	if bytes < minPanicStackSize {
+		bytes = minPanicStackSize
+	}
+	atomic.StoreInt32(&c.stack, int32(bytes))
+}
+
+// panicStackSize returns the configured panic stack trace buffer size.
+func (c *Cron) panicStackSize() int {
+	if size := atomic.LoadInt32(&c.stack); size > 0 {
+		return int(size)
+	}
+	return defaultPanicStackSize
+}
+
+// runWithRecovery runs j, recovering from and logging a panic. The name
+// identifies the job in the log when it returns an error, which is returned,
+// and ctx is passed to a JobWithContext.
+func (c *Cron) runWithRecovery(ctx context.Context, name string, j Job) (err error) {
+	atomic.AddInt32(&c.active, 1)
+	start, began := c.now(), time.Now()
+	if c.OnJobStart != nil {
+		c.OnJobStart(name, start)
+	}
 	defer func() {
-		if r := recover(); r != nil {
-			const size = 64 << 10
-			buf := make([]byte, size)
+		atomic.AddInt32(&c.active, -1)
+		var buf []byte
+		r := recover()
+		if r != nil {
+			buf = make([]byte, c.panicStackSize())
 			buf = buf[:runtime.Stack(buf, false)]
 			c.logf("cron: panic running job: %v\n%s", r, buf)
 		}
+		if c.OnJobComplete != nil {
+			c.OnJobComplete(name, start, time.Since(began), r, buf)
+		}
 	}()
-	j.Run()
+	switch j := j.(type) {
+	case errorJob:
+		if err = j.job.Run(); err != nil {
+			c.logf("cron: job %q failed: %v", name, err)
+		}
+	case contextJob:
+		j.job.Run(ctx)
+	default:
+		j.Run()
+	}
+	return err
 }
 
 // Run the scheduler. this is private just due to the need to synchronize
@@ -249,60 +1464,39 @@ func (c *Cron) run() {
 	// Figure out the next activation times for each entry.
 	now := c.now()
 	for _, entry := range c.entries {
-		entry.Next = entry.Schedule.RandomNext(now, entry.DelayRange)
+		c.scheduleNext(entry, now)
 	}
+	heap.Init(c.queue())
 
 	for {
-		// Determine the next entry to run.
-		sort.Sort(byTime(c.entries))
-
-		var timer *time.Timer
-		if len(c.entries) == 0 || c.entries[0].Next.IsZero() {
-			// If there are no entries yet, just sleep - it still handles new entries
-			// and stop requests.
-			timer = time.NewTimer(100000 * time.Hour)
-		} else {
-			timer = time.NewTimer(c.entries[0].Next.Sub(now))
-		}
+		// The entry to run next is at the root of the heap.
+		timer := timerUntil(c.soonest(), now)
 
 		for {
 			select {
-			case now = <-timer.C:
-				now = now.In(c.location)
-				// Run every entry whose next time was less than now
-				for _, e := range c.entries {
-					if e.Next.After(now) || e.Next.IsZero() {
-						break
-					}
-					go c.runWithRecovery(e.Job)
-					e.Prev = e.Next
-					e.Next = e.Schedule.RandomNext(now, e.DelayRange)
-				}
-
-			case newEntry := <-c.add:
-				if newEntry.Name != "" && pos(c.entries, newEntry.Name) != -1 {
-					continue // 已经存在同名任务
-				}
+			case <-timer.C:
+				now = c.now()
+				c.runDue(now)
 
+			case op := <-c.ops:
 				timer.Stop()
 				now = c.now()
-				newEntry.Next = newEntry.Schedule.RandomNext(now, newEntry.DelayRange)
-				c.entries = append(c.entries, newEntry)
-
-			case name := <-c.remove:
-				i := pos(c.entries, name)
-				if i == -1 {
-					continue
-				}
-				timer.Stop()
-				c.entries = removeEntry(c.entries, i)
+				op()
+				c.drain()
 
 			case <-c.snapshot:
-				c.snapshot <- c.entrySnapshot()
+				c.snapshot <- snapshot{c.entrySnapshot(), c.now()}
+				continue
+
+			case <-c.statsTicks():
+				go c.statsFn(c.currentStats())
 				continue
 
 			case <-c.stop:
+				// The stopping goroutine runs the shutdown jobs; Run
+				// returns once they are done.
 				timer.Stop()
+				<-c.stopped
 				return
 			}
 
@@ -311,6 +1505,188 @@ func (c *Cron) run() {
 	}
 }
 
+// timerUntil returns a timer that fires at next, or at once if next is not
+// after now. If next is the zero time, as when there are no entries yet, the
+// timer practically never fires: the loop still handles ops and stop requests.
+func timerUntil(next, now time.Time) *time.Timer {
+	if next.IsZero() {
+		return time.NewTimer(100000 * time.Hour)
+	}
+	d := next.Sub(now)
+	if d < 0 {
+		d = 0
+	}
+	return time.NewTimer(d)
+}
+
+// drain handles the op requests, such as adds, that are immediately
+// available, so that a burst of them is applied before the timer is rebuilt
+// once.
+func (c *Cron) drain() {
+	for {
+		select {
+		case op := <-c.ops:
+			op()
+
+		default:
+			return
+		}
+	}
+}
+
+// Stats is a summary of the state of a Cron.
+type Stats struct {
+	Entries int       // Number of entries
+	Active  int       // Number of jobs running right now
+	Runs    int64     // Number of runs started in total
+	Next    time.Time // Soonest next activation, or the zero time if none
+}
+
+// Stats returns a summary of the current state of the Cron.
+func (c *Cron) Stats() Stats {
+	var st Stats
+	c.do(func() { st = c.currentStats() })
+	return st
+}
+
+// SetStatsInterval arranges for fn to be called with the Cron's Stats every
+// d while the Cron is running, for push-based metrics. fn is called in its
+// own goroutine. A zero d or nil fn disables it. It is not supported for a
+// Cron obtained from a SharedScheduler, which has no loop of its own to
+// drive it.
+func (c *Cron) SetStatsInterval(d time.Duration, fn func(Stats)) {
+	c.do(func() {
+		if c.stats != nil {
+			c.stats.Stop()
+			c.stats = nil
+		}
+		c.statsFn = fn
+		if d > 0 && fn != nil {
+			c.stats = time.NewTicker(d)
+		}
+	})
+}
+
+// currentStats computes the Stats; the caller must have access to the entries.
+func (c *Cron) currentStats() Stats {
+	st := Stats{
+		Entries: len(c.entries),
+		Active:  c.ActiveCount(),
+		Runs:    atomic.LoadInt64(&c.runs),
+	}
+	if len(c.entries) > 0 {
+		st.Next = c.entries[0].Next
+	}
+	return st
+}
+
+// statsTicks returns the channel of the stats ticker, or nil if it is
+// disabled, which blocks forever in a select.
+func (c *Cron) statsTicks() <-chan time.Time {
+	if c.stats == nil {
+		return nil
+	}
+	return c.stats.C
+}
+
+// runDue runs every entry whose next time is not after now, plus the early
+// fire tolerance, and computes its following activation. Due entries are
+// taken off the heap before any is rescheduled, so each runs at most once per
+// call.
+func (c *Cron) runDue(now time.Time) {
+	var (
+		due    []*Entry
+		done   []string
+		cutoff = now.Add(c.early)
+	)
+	c.runRetries(cutoff)
+	for len(c.entries) > 0 {
+		e := c.entries[0]
+		if e.Next.After(cutoff) || e.Next.IsZero() {
+			break
+		}
+		due = append(due, heap.Pop(c.queue()).(*Entry))
+	}
+	for _, e := range due {
+		switch {
+		case e.Paused:
+			c.skip(e, "paused")
+		case e.RunIf != nil && !e.RunIf():
+			c.skip(e, "condition")
+		case e.SkipIfRunning && atomic.LoadInt32(&e.running) > 0:
+			c.skip(e, "running")
+			c.logf("cron: skipped %s, still running", e)
+		case c.dryRun:
+			c.logf("cron: dry run, would run %s", e)
+		case e.QueueIfRunning && !e.RunInline && !c.queueRun(e):
+			// Queued behind the run in progress, or skipped.
+		default:
+			e.RunCount++
+			atomic.AddInt64(&c.runs, 1)
+			if e.RunInline {
+				c.runInline(e)
+			} else {
+				c.startJob(e)
+			}
+		}
+		e.Prev = e.Next
+		// An entry run early is rescheduled after the time it was due, so
+		// that it does not run again for the same activation.
+		if e.Next.After(now) {
+			c.scheduleNext(e, e.Next)
+		} else {
+			c.scheduleNext(e, now)
+		}
+		// A schedule that does not advance past now would be due again at
+		// once, and the loop would spin running it, so it is not run again.
+		if !e.Next.IsZero() && !e.Next.After(now) {
+			c.logf("cron: schedule of %s does not advance past %v, not running it again", e, now)
+			e.Next = time.Time{}
+		}
+		if e.Next.IsZero() && e.RemoveWhenDone {
+			done = append(done, e.Name)
+			continue
+		}
+		heap.Push(c.queue(), e)
+	}
+	if len(done) > 0 {
+		c.removed()
+		if c.OnEntryDone != nil {
+			for _, name := range done {
+				go c.OnEntryDone(name)
+			}
+		}
+	}
+}
+
+// queue returns the entries as a heap ordered by next activation time.
+func (c *Cron) queue() *byTime {
+	return (*byTime)(&c.entries)
+}
+
+// skip records that the entry was due but not run, for the given reason.
+func (c *Cron) skip(e *Entry, reason string) {
+	e.SkipCount++
+	if e.SkipReasons == nil {
+		e.SkipReasons = make(map[string]int)
+	}
+	e.SkipReasons[reason]++
+}
+
+// scheduleNext sets the entry's next activation times after now, both with
+// and without its random delay.
+func (c *Cron) scheduleNext(e *Entry, now time.Time) {
+	e.BaseNext = e.Schedule.Next(now)
+	if c.rand != nil {
+		e.Next = randomNextFrom(e.Schedule, now, e.DelayRange, c.rand.Int63n)
+	} else {
+		e.Next = e.Schedule.RandomNext(now, e.DelayRange)
+	}
+	if gap := c.spread[e.Group]; e.Group != "" && gap > 0 {
+		c.spreadNext(e, gap)
+	}
+}
+
 // Logs an error to stderr or to the configured error log
 func (c *Cron) logf(format string, args ...interface{}) {
 	if c.ErrorLog != nil {
@@ -321,30 +1697,162 @@ func (c *Cron) logf(format string, args ...interface{}) {
 }
 
 // Stop stops the cron scheduler if it is running; otherwise it does nothing.
+// It cancels the context given to running jobs that take one, but does not
+// wait for any job to return.
 func (c *Cron) Stop() {
+	c.stopWith(-1)
+}
+
+// stopWith stops the scheduler, then runs the shutdown jobs once the running
+// jobs have returned or wait has elapsed, or right away if wait is negative.
+// ok is false if the scheduler was not running.
+func (c *Cron) stopWith(wait time.Duration) (ok bool, err error) {
+	if c.shared != nil {
+		return c.shared.stop(c, wait)
+	}
+	c.mu.Lock()
 	if !c.running {
-		return
+		c.mu.Unlock()
+		return false, nil
 	}
 	c.stop <- struct{}{}
 	c.running = false
+	shutdown := c.shutdown
+	c.retries = nil
+	c.mu.Unlock()
+	c.cancelJobs()
+
+	// Running jobs may call back into the Cron while they are waited for,
+	// so the lock is released first.
+	if wait >= 0 {
+		err = c.waitJobs(wait)
+	}
+	c.runShutdownJobs(shutdown)
+	c.stopped <- struct{}{}
+	return true, err
+}
+
+// ErrStopTimeout is returned by StopAndWait when jobs are still running once
+// the timeout has elapsed.
+var ErrStopTimeout = errors.New("cron: timed out waiting for running jobs")
+
+// StopAndWait stops the cron scheduler, then blocks until every job it started
+// has returned or the timeout elapses, in which case it returns ErrStopTimeout.
+// Jobs still running at that point are not interrupted, though the context of
+// those that take one is cancelled as with Stop. The shutdown jobs run
+// last, after the wait.
+func (c *Cron) StopAndWait(timeout time.Duration) error {
+	if ok, err := c.stopWith(timeout); ok {
+		return err
+	}
+	return c.waitJobs(timeout)
 }
 
-// entrySnapshot returns a copy of the current cron entry list.
+// waitJobs blocks until every job started has returned, or returns
+// ErrStopTimeout once the timeout elapses.
+func (c *Cron) waitJobs(timeout time.Duration) error {
+	done := make(chan struct{})
+	go func() {
+		c.jobs.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		return ErrStopTimeout
+	}
+}
+
+// entrySnapshot returns a copy of the current cron entry list, sorted by
+// next activation time.
 func (c *Cron) entrySnapshot() []*Entry {
 	entries := []*Entry{}
 	for _, e := range c.entries {
-		entries = append(entries, &Entry{
-			Schedule: e.Schedule,
-			Next:     e.Next,
-			Prev:     e.Prev,
-			Job:      e.Job,
-			Name:     e.Name,
-		})
+		entries = append(entries, copyEntry(e))
 	}
+	sort.Sort(byTime(entries))
 	return entries
 }
 
+// copyEntry returns a copy of e that shares no mutable state with it.
+func copyEntry(e *Entry) *Entry {
+	return &Entry{
+		Schedule: e.Schedule,
+		Next:     e.Next,
+		BaseNext: e.BaseNext,
+		Prev:     e.Prev,
+		Job:      e.Job,
+		Name:     e.Name,
+
+		DelayRange:  e.DelayRange,
+		Description: e.Description,
+		Spec:        e.Spec,
+		RunCount:    e.RunCount,
+		SkipCount:   e.SkipCount,
+		SkipReasons: copyCounts(e.SkipReasons),
+		RunIf:       e.RunIf,
+		RunInline:   e.RunInline,
+
+		SkipIfRunning:  e.SkipIfRunning,
+		QueueIfRunning: e.QueueIfRunning,
+		MaxQueued:      e.MaxQueued,
+		Group:          e.Group,
+		Paused:         e.Paused,
+		Location:       e.Location,
+		MaxRetries:     e.MaxRetries,
+		RetryBackoff:   e.RetryBackoff,
+		RemoveWhenDone: e.RemoveWhenDone,
+
+		seq: e.seq,
+	}
+}
+
+// copyCounts returns a copy of m, or nil if m is empty.
+func copyCounts(m map[string]int) map[string]int {
+	if len(m) == 0 {
+		return nil
+	}
+	cp := make(map[string]int, len(m))
+	for k, v := range m {
+		cp[k] = v
+	}
+	return cp
+}
+
+// Clock tells the current time. It lets tests drive a Cron with a fake clock
+// instead of the system time.
+type Clock interface {
+	Now() time.Time
+}
+
+// SetClock makes the Cron read the current time from clk instead of the
+// system clock, which a nil clk restores. The scheduler still sleeps in real
+// time between activations, so it notices a fake clock moving forward once
+// its timer for the next activation, computed from clk, fires. It is meant to
+// be called before the Cron is started.
+func (c *Cron) SetClock(clk Clock) {
+	c.do(func() { c.clock = clk })
+}
+
+// SetRand makes the Cron draw the random delays of its entries, see
+// Entry.DelayRange, from r instead of crypto/rand, so that two Crons given
+// sources with the same seed compute the same delayed activations. r is only
+// used by the scheduler, so it needs no locking, but must not be shared
+// with other Crons or code. Schedules of types defined outside this package
+// are delayed by up to DelayRange, never reaching their following
+// activation, rather than through their RandomNext. A nil r restores the
+// default.
+func (c *Cron) SetRand(r *rand.Rand) {
+	c.do(func() { c.rand = r })
+}
+
 // now returns current time in c location
 func (c *Cron) now() time.Time {
-	return time.Now().In(c.location)
+	if c.clock != nil {
+		return c.clock.Now().In(c.Location())
+	}
+	return time.Now().In(c.Location())
 }
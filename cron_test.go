@@ -1,8 +1,20 @@
 package cron
 
 import (
+	"bytes"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -270,6 +282,125 @@ func TestStopWithoutStart(t *testing.T) {
 	cron.Stop()
 }
 
+// Test that StopAndWait blocks until a running job has returned, and only then
+// runs the shutdown jobs.
+func TestStopAndWait(t *testing.T) {
+	started := make(chan struct{})
+	var finished int32
+	cron := New()
+	cron.AddFunc("* * * * * ?", func() {
+		select {
+		case started <- struct{}{}:
+		default:
+			return
+		}
+		time.Sleep(200 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+	})
+	var finishedAtShutdown int32 = -1
+	cron.AddShutdownJob("check", func() {
+		atomic.StoreInt32(&finishedAtShutdown, atomic.LoadInt32(&finished))
+	})
+	cron.Start()
+
+	select {
+	case <-time.After(OneSecond):
+		t.Fatal("expected the job to start")
+	case <-started:
+	}
+	if err := cron.StopAndWait(OneSecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Error("expected StopAndWait to return after the job finished")
+	}
+	if atomic.LoadInt32(&finishedAtShutdown) != 1 {
+		t.Error("expected the shutdown job to run after the job finished")
+	}
+}
+
+// Test that StopAndWait gives up once the timeout elapses.
+func TestStopAndWaitTimeout(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	cron := New()
+	cron.Submit("slow", func() {
+		close(started)
+		<-release
+	})
+	<-started
+	if err := cron.StopAndWait(50 * time.Millisecond); err != ErrStopTimeout {
+		t.Errorf("expected ErrStopTimeout, got %v", err)
+	}
+}
+
+// Test that adding and listing entries concurrently with starting and stopping
+// is safe, and that no add is lost on either side of a Stop. Run it with -race.
+func TestConcurrentStartStop(t *testing.T) {
+	const workers, adds = 4, 100
+	cron := New()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < adds; j++ {
+				cron.AddFunc("0 0 0 1 1 ?", func() {})
+				cron.Entries()
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		cron.Start()
+		cron.Stop()
+	}
+	wg.Wait()
+	if n := len(cron.Entries()); n != workers*adds {
+		t.Errorf("expected %d entries, got %d", workers*adds, n)
+	}
+}
+
+// Test that adds, removals and snapshots racing with Stop never block: every
+// request either reaches the scheduler before it stops or is applied directly
+// once it has.
+func TestStopDuringOps(t *testing.T) {
+	const workers, ops = 4, 200
+	for round := 0; round < 20; round++ {
+		cron := New()
+		cron.Start()
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < ops; j++ {
+					name := fmt.Sprint(i, "-", j)
+					cron.AddNameFunc(name, "0 0 0 1 1 ?", func() {})
+					cron.SnapshotAt()
+					if j%2 == 0 {
+						cron.RemoveJob(name)
+					}
+				}
+			}(i)
+		}
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		cron.Stop()
+
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("expected operations racing with Stop not to block")
+		}
+		if n := cron.EntryCount(); n != workers*ops/2 {
+			t.Fatalf("expected %d entries, got %d", workers*ops/2, n)
+		}
+	}
+}
+
 type testJob struct {
 	wg   *sync.WaitGroup
 	name string
@@ -417,3 +548,1779 @@ func stop(cron *Cron) chan bool {
 	}()
 	return ch
 }
+
+// Test that a spec is parsed with the given parser rather than the default.
+func TestAddFuncWithParser(t *testing.T) {
+	cron := New()
+	if err := cron.AddFuncWithParser("standard", "30 8 * * 1-5", standardParser, func() {}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cron.AddFuncWithParser("seconds", "0 30 8 * * 1-5", standardParser, func() {}); err == nil {
+		t.Error("expected an error parsing a seconds field with the standard parser")
+	}
+
+	expected, _ := ParseStandard("30 8 * * 1-5")
+	entries := cron.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	if !reflect.DeepEqual(entries[0].Schedule, expected) {
+		t.Errorf("expected schedule %v, got %v", expected, entries[0].Schedule)
+	}
+}
+
+// Test that OnEmpty fires once when the last entry is removed.
+func TestOnEmpty(t *testing.T) {
+	emptied := make(chan struct{}, 2)
+
+	cron := New()
+	cron.OnEmpty = func() { emptied <- struct{}{} }
+	cron.AddNameFunc("a", "0 0 0 1 1 ?", func() {})
+	cron.AddNameFunc("b", "0 0 0 1 1 ?", func() {})
+	cron.Start()
+	defer cron.Stop()
+
+	cron.RemoveJob("a")
+	cron.RemoveJob("missing")
+	select {
+	case <-emptied:
+		t.Fatal("expected OnEmpty not to fire while entries remain")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	cron.RemoveJob("b")
+	select {
+	case <-emptied:
+	case <-time.After(OneSecond):
+		t.Fatal("expected OnEmpty to fire after the last entry was removed")
+	}
+
+	cron.RemoveJob("b")
+	select {
+	case <-emptied:
+		t.Error("expected OnEmpty to fire only on the transition to empty")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+// churnJob is a job whose collection is observed through a finalizer.
+type churnJob struct{ _ [16]byte }
+
+func (*churnJob) Run() {}
+
+// Test that removed entries do not keep their jobs reachable through the
+// entries slice, and that the slice shrinks after heavy removal.
+func TestRemovedEntriesCollectible(t *testing.T) {
+	var collected int32
+	cron := New()
+	for i := 0; i < 100; i++ {
+		job := &churnJob{}
+		runtime.SetFinalizer(job, func(*churnJob) { atomic.AddInt32(&collected, 1) })
+		cron.AddNameJob(fmt.Sprintf("job%d", i), "0 0 0 1 1 ?", job)
+	}
+
+	for i := 0; i < 10; i++ {
+		cron.RemoveJob(fmt.Sprintf("job%d", i))
+	}
+	for i := 0; i < 20 && atomic.LoadInt32(&collected) < 10; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&collected); n != 10 {
+		t.Errorf("expected the 10 removed jobs to be collected, got %d", n)
+	}
+
+	for i := 10; i < 95; i++ {
+		cron.RemoveJob(fmt.Sprintf("job%d", i))
+	}
+	if c := cap(cron.entries); c > minShrinkCap {
+		t.Errorf("expected the entries slice to shrink, capacity is %d", c)
+	}
+	if n := len(cron.Entries()); n != 5 {
+		t.Errorf("expected 5 entries to remain, got %d", n)
+	}
+}
+
+// Test that snapshots expose the schedule time before the random delay.
+func TestSnapshotBaseNext(t *testing.T) {
+	sched, _ := Parse("0 * * * * *")
+
+	cron := New()
+	cron.NameAndDelaySchedule("delayed", sched, 50, FuncJob(func() {}))
+	cron.Start()
+	defer cron.Stop()
+
+	e := cron.Entries()[0]
+	if e.BaseNext.IsZero() || e.BaseNext.Second() != 0 {
+		t.Errorf("expected BaseNext on a whole minute, got %v", e.BaseNext)
+	}
+	if delay := e.Next.Sub(e.BaseNext); delay < 0 || delay >= 50*time.Second {
+		t.Errorf("expected a delay within [0s, 50s), got %v", delay)
+	}
+}
+
+// BenchmarkTick measures the per-tick scheduling cost with many entries, one
+// of which is due on each tick. "sort" reproduces re-sorting every entry on
+// each tick, for comparison with the heap the scheduler uses.
+func BenchmarkTick(b *testing.B) {
+	for _, n := range []int{1000, 50000} {
+		setup := func() (*Cron, time.Time) {
+			cron := New()
+			start := time.Now()
+			for i := 0; i < n; i++ {
+				sched := Every(time.Duration(n) * time.Second)
+				e := &Entry{Schedule: sched, Job: FuncJob(func() {})}
+				e.Next = start.Add(time.Duration(i) * time.Second)
+				cron.entries = append(cron.entries, e)
+			}
+			heap.Init(cron.queue())
+			return cron, start
+		}
+
+		b.Run(fmt.Sprintf("heap/%d", n), func(b *testing.B) {
+			cron, _ := setup()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cron.runDue(cron.entries[0].Next)
+			}
+		})
+
+		b.Run(fmt.Sprintf("sort/%d", n), func(b *testing.B) {
+			cron, _ := setup()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				sort.Sort(byTime(cron.entries))
+				e := cron.entries[0]
+				e.Prev = e.Next
+				cron.scheduleNext(e, e.Next)
+			}
+		})
+	}
+}
+
+// Test that operations on unknown names are reported to the handler.
+func TestUnknownNameHandler(t *testing.T) {
+	var reported []string
+	cron := New()
+	cron.SetUnknownNameHandler(func(op, name string) {
+		reported = append(reported, op+" "+name)
+	})
+	cron.AddNameFunc("known", "0 0 0 1 1 ?", func() {})
+
+	cron.RemoveJob("missing-before-start")
+	cron.Start()
+	cron.RemoveJob("known")
+	cron.RemoveJob("missing-while-running")
+	cron.SetRunIf("missing", func() bool { return true })
+	cron.SetRunInline("missing", true)
+	cron.TriggerNow("missing")
+	cron.Pause("missing")
+	cron.Resume("missing")
+	cron.Stop()
+
+	expected := []string{
+		"remove missing-before-start",
+		"remove missing-while-running",
+		"condition missing",
+		"inline missing",
+		"trigger missing",
+		"pause missing",
+		"resume missing",
+	}
+	if !reflect.DeepEqual(reported, expected) {
+		t.Errorf("expected %v, got %v", expected, reported)
+	}
+
+	// Without a handler unknown names are silently ignored.
+	cron.SetUnknownNameHandler(nil)
+	cron.RemoveJob("missing")
+}
+
+// Test that skipped runs are counted per reason and copied into snapshots.
+func TestSkippedRuns(t *testing.T) {
+	cron := New()
+	cron.AddNameFunc("job", "0 0 0 1 1 ?", func() {})
+	e := cron.entries[0]
+	cron.skip(e, "overlap")
+	cron.skip(e, "overlap")
+	cron.skip(e, "paused")
+
+	snap := cron.Entries()[0]
+	if snap.SkippedRuns() != 3 {
+		t.Errorf("expected 3 skipped runs, got %d", snap.SkippedRuns())
+	}
+	expected := map[string]int{"overlap": 2, "paused": 1}
+	if !reflect.DeepEqual(snap.SkipReasons, expected) {
+		t.Errorf("expected reasons %v, got %v", expected, snap.SkipReasons)
+	}
+
+	cron.skip(e, "overlap")
+	if snap.SkipReasons["overlap"] != 2 {
+		t.Error("expected the snapshot to be independent of the entry")
+	}
+}
+
+// Test that SnapshotAt returns the scheduler's time along with the entries.
+func TestSnapshotAt(t *testing.T) {
+	cron := New()
+	cron.AddNameFunc("job", "@every 1h", func() {})
+
+	before := time.Now()
+	entries, now := cron.SnapshotAt()
+	if len(entries) != 1 || now.Before(before) || now.Location() != cron.Location() {
+		t.Errorf("unexpected snapshot before start: %v at %v", entries, now)
+	}
+
+	cron.Start()
+	defer cron.Stop()
+	entries, now = cron.SnapshotAt()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	if !entries[0].Next.After(now) {
+		t.Errorf("expected entry not to be overdue: next %v, now %v", entries[0].Next, now)
+	}
+}
+
+// Test that a failed upstream job suppresses a downstream job gated on it.
+func TestRunIf(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		lastErr error
+	)
+	succeeded := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return lastErr == nil
+	}
+
+	ran := make(chan struct{}, 10)
+	cron := New()
+	cron.AddNameFunc("export", "0 0 0 1 1 ?", func() {
+		mu.Lock()
+		lastErr = errors.New("export failed")
+		mu.Unlock()
+	})
+	cron.AddNameFunc("cleanup", "* * * * * ?", func() { ran <- struct{}{} })
+	cron.SetRunIf("cleanup", succeeded)
+
+	cron.entries[pos(cron.entries, "export")].Job.Run()
+	cron.Start()
+	defer cron.Stop()
+
+	select {
+	case <-ran:
+		t.Fatal("expected cleanup not to run after a failed export")
+	case <-time.After(OneSecond):
+	}
+	for _, e := range cron.Entries() {
+		if e.Name == "cleanup" && e.SkipReasons["condition"] == 0 {
+			t.Error("expected the suppressed run to be counted")
+		}
+	}
+
+	mu.Lock()
+	lastErr = nil
+	mu.Unlock()
+	select {
+	case <-ran:
+	case <-time.After(OneSecond):
+		t.Fatal("expected cleanup to run once the export succeeded")
+	}
+}
+
+// Test that due jobs are handed to a custom dispatcher, and that the closure
+// it receives still recovers from panics.
+func TestSetDispatcher(t *testing.T) {
+	pool := make(chan func(), 10)
+	cron := New()
+	cron.SetDispatcher(func(job func()) { pool <- job })
+	cron.AddFunc("* * * * * ?", func() { panic("YOLO") })
+	cron.Start()
+	defer cron.Stop()
+
+	select {
+	case job := <-pool:
+		job()
+	case <-time.After(OneSecond):
+		t.Fatal("expected the job to be dispatched")
+	}
+}
+
+func TestAddFuncWithDesc(t *testing.T) {
+	cron := New()
+	if err := cron.AddFuncWithDesc("job-42", "nightly export", "0 0 0 * * ?", func() {}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cron.AddFuncWithDesc("bad", "", "bad spec", func() {}); err == nil {
+		t.Error("expected an error for an invalid spec")
+	}
+
+	e := cron.Entries()[0]
+	if e.Description != "nightly export" {
+		t.Errorf("expected the description in the snapshot, got %q", e.Description)
+	}
+	if s := e.String(); s != "job-42 (nightly export) next: never" {
+		t.Errorf("unexpected String before start: %q", s)
+	}
+
+	cron.Start()
+	defer cron.Stop()
+	e = cron.Entries()[0]
+	if expected := "job-42 (nightly export) next: " + e.Next.Format(time.RFC3339); e.String() != expected {
+		t.Errorf("expected %q, got %q", expected, e.String())
+	}
+
+	cron.do(func() { cron.entries[0].Paused = true })
+	paused := cron.Entries()[0]
+	if expected := "job-42 (nightly export) next: " + paused.Next.Format(time.RFC3339) + " (paused)"; paused.String() != expected {
+		t.Errorf("expected %q, got %q", expected, paused.String())
+	}
+	if e.Paused {
+		t.Error("expected earlier snapshots not to see the paused state")
+	}
+}
+
+// Test that shutdown jobs run once, in order, by the time Stop returns.
+func TestAddShutdownJob(t *testing.T) {
+	var ran []string
+	cron := New()
+	cron.AddShutdownJob("flush", func() { ran = append(ran, "flush") })
+	cron.Start()
+	cron.AddShutdownJob("close", func() { ran = append(ran, "close") })
+	cron.AddShutdownJob("panic", func() { panic("YOLO") })
+
+	cron.Stop()
+	if expected := []string{"flush", "close"}; !reflect.DeepEqual(ran, expected) {
+		t.Errorf("expected shutdown jobs %v, got %v", expected, ran)
+	}
+	cron.Stop()
+	if len(ran) != 2 {
+		t.Errorf("expected shutdown jobs to run once, got %v", ran)
+	}
+}
+
+type deepPanicJob int
+
+func (d deepPanicJob) Run() {
+	if d == 0 {
+		panic("YOLO")
+	}
+	(d - 1).Run()
+}
+
+// Test that errors returned by error jobs are logged with the entry name,
+// while successful runs log nothing.
+func TestAddErrorFunc(t *testing.T) {
+	var (
+		buf  syncWriter
+		wg   sync.WaitGroup
+		fail = errors.New("disk full")
+	)
+	wg.Add(2)
+	cron := New()
+	cron.ErrorLog = log.New(&buf, "", 0)
+	cron.AddNameErrorFunc("backup", "* * * * * ?", func() error {
+		defer wg.Done()
+		return fail
+	})
+	cron.AddErrorFunc("* * * * * ?", func() error {
+		defer wg.Done()
+		return nil
+	})
+	cron.Start()
+	defer cron.Stop()
+
+	select {
+	case <-time.After(OneSecond):
+		t.Fatal("expected both error jobs to run")
+	case <-wait(&wg):
+	}
+	time.Sleep(10 * time.Millisecond)
+	if got, want := strings.TrimSpace(buf.String()), `cron: job "backup" failed: disk full`; got != want {
+		t.Errorf("expected log %q, got %q", want, got)
+	}
+}
+
+// failingJob is an ErrorJob that always fails with its message.
+type failingJob string
+
+func (j failingJob) Run() error { return errors.New(string(j)) }
+
+// Test that errors from a user-defined ErrorJob are logged with the entry name.
+func TestAddErrorJob(t *testing.T) {
+	var buf syncWriter
+	cron := New()
+	cron.ErrorLog = log.New(&buf, "", 0)
+	if err := cron.AddNameErrorJob("sync", "* * * * * ?", failingJob("timeout")); err != nil {
+		t.Fatal(err)
+	}
+	if err := cron.AddErrorJob("* * * * * ?", failingJob("refused")); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	cron.Tick(now)
+	cron.Tick(now.Add(time.Second))
+	cron.jobs.Wait()
+	for _, want := range []string{`cron: job "sync" failed: timeout`, `cron: job "" failed: refused`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected log %q, got %q", want, buf.String())
+		}
+	}
+}
+
+// Test that the panic stack trace is captured with the configured buffer size.
+func TestSetPanicStackSize(t *testing.T) {
+	var buf bytes.Buffer
+	cron := New()
+	cron.ErrorLog = log.New(&buf, "", 0)
+
+	cron.runWithRecovery(context.Background(), "", deepPanicJob(100))
+	if buf.Len() <= 2*minPanicStackSize {
+		t.Fatalf("expected a deep stack trace by default, got %d bytes", buf.Len())
+	}
+
+	buf.Reset()
+	cron.SetPanicStackSize(1)
+	cron.runWithRecovery(context.Background(), "", deepPanicJob(100))
+	if buf.Len() > 2*minPanicStackSize || !strings.Contains(buf.String(), "YOLO") {
+		t.Errorf("expected the trace to be truncated to the minimum size, got %d bytes", buf.Len())
+	}
+}
+
+// Test that entries due shortly after a run are batched into it.
+func TestEarlyFireTolerance(t *testing.T) {
+	ran := make(chan struct{}, 10)
+	cron := New()
+	cron.SetEarlyFireTolerance(2 * time.Second)
+	cron.AddFunc("* * * * * ?", func() {})
+	cron.AddNameFunc("later", "@every 3s", func() { ran <- struct{}{} })
+	cron.Start()
+	defer cron.Stop()
+
+	select {
+	case <-ran:
+	case <-time.After(OneSecond):
+		t.Fatal("expected the later job to run early with the first one")
+	}
+	for _, e := range cron.Entries() {
+		if e.Name == "later" && !e.Next.After(e.Prev) {
+			t.Errorf("expected the early run to be rescheduled after %v, got %v", e.Prev, e.Next)
+		}
+	}
+	select {
+	case <-ran:
+		t.Error("expected the later job to run once for its activation")
+	case <-time.After(OneSecond):
+	}
+}
+
+// Test that jobs are not run in dry-run mode, while their schedule advances.
+func TestDryRun(t *testing.T) {
+	var buf syncWriter
+	ran := make(chan struct{}, 10)
+	cron := New()
+	cron.ErrorLog = log.New(&buf, "", 0)
+	cron.SetDryRun(true)
+	cron.AddNameFunc("job", "* * * * * ?", func() { ran <- struct{}{} })
+	cron.Start()
+	defer cron.Stop()
+
+	select {
+	case <-ran:
+		t.Fatal("expected the job not to run in dry-run mode")
+	case <-time.After(OneSecond):
+	}
+	if e := cron.Entries()[0]; e.Prev.IsZero() {
+		t.Error("expected Prev to advance in dry-run mode")
+	} else if e.RunCount != 0 || e.SkipCount != 0 {
+		t.Errorf("expected dry runs not to be counted, got %d runs and %d skips", e.RunCount, e.SkipCount)
+	}
+	if !strings.Contains(buf.String(), "would run job") {
+		t.Errorf("expected the intended run to be logged, got %q", buf.String())
+	}
+
+	cron.SetDryRun(false)
+	select {
+	case <-ran:
+	case <-time.After(OneSecond):
+		t.Fatal("expected the job to run once dry-run mode is disabled")
+	}
+}
+
+// syncWriter is a bytes.Buffer safe for concurrent use.
+type syncWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *syncWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestSpecString(t *testing.T) {
+	cron := New()
+	cron.AddNameFunc("parsed", "0 30 * * * ?", func() {})
+	cron.NameAndDelaySchedule("every", Every(90*time.Minute), 0, FuncJob(func() {}))
+	weekly, _ := Parse("0 30 9 * * MON")
+	cron.NameAndDelaySchedule("weekly", weekly, 0, FuncJob(func() {}))
+	cron.NameAndDelaySchedule("custom", &ZeroSchedule{}, 0, FuncJob(func() {}))
+	cron.NameAndDelaySchedule("once", At(time.Now().Add(time.Hour)), 0, FuncJob(func() {}))
+
+	expected := map[string]struct {
+		spec string
+		ok   bool
+	}{
+		"parsed": {"0 30 * * * ?", true},
+		"every":  {"@every 1h30m0s", true},
+		"weekly": {"0 30 9 * * 1", true},
+		"custom": {"", false},
+		"once":   {"", false},
+	}
+	for _, e := range cron.Entries() {
+		spec, ok := e.SpecString()
+		if x := expected[e.Name]; spec != x.spec || ok != x.ok {
+			t.Errorf("%s: expected (%q, %v), got (%q, %v)", e.Name, x.spec, x.ok, spec, ok)
+		}
+	}
+}
+
+func TestActiveCount(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	cron := New()
+	cron.AddFunc("* * * * * ?", func() {
+		select {
+		case started <- struct{}{}:
+			<-release
+		default:
+		}
+	})
+	cron.Start()
+	defer cron.Stop()
+
+	select {
+	case <-started:
+	case <-time.After(OneSecond):
+		t.Fatal("expected the job to start")
+	}
+	if n := cron.ActiveCount(); n != 1 {
+		t.Errorf("expected 1 active job, got %d", n)
+	}
+	close(release)
+	for i := 0; i < 100 && cron.ActiveCount() != 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if n := cron.ActiveCount(); n != 0 {
+		t.Errorf("expected no active jobs once the job returned, got %d", n)
+	}
+}
+
+func TestNextEntry(t *testing.T) {
+	cron := New()
+	cron.AddNameFunc("yearly", "0 0 0 1 1 ?", func() {})
+	cron.AddNameFunc("hourly", "0 0 * * * ?", func() {})
+	if _, _, ok := cron.NextEntry(); ok {
+		t.Error("expected no next entry before start")
+	}
+
+	cron.Start()
+	defer cron.Stop()
+	name, next, ok := cron.NextEntry()
+	if !ok || name != "hourly" {
+		t.Fatalf("expected the hourly entry next, got %q (ok %v)", name, ok)
+	}
+	if e := cron.Entries()[0]; !next.Equal(e.Next) {
+		t.Errorf("expected next %v, got %v", e.Next, next)
+	}
+}
+
+// Test that a burst of concurrent adds to a running Cron is fully applied,
+// and that the timer reflects the entries added in the batch.
+func TestAddBurstWhileRunning(t *testing.T) {
+	cron := New()
+	cron.Start()
+	defer cron.Stop()
+
+	ran := make(chan struct{}, 1)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i == 50 {
+				cron.AddFunc("* * * * * ?", func() {
+					select {
+					case ran <- struct{}{}:
+					default:
+					}
+				})
+				return
+			}
+			cron.AddFunc("0 0 0 1 1 ?", func() {})
+		}(i)
+	}
+	wg.Wait()
+
+	if n := len(cron.Entries()); n != 100 {
+		t.Errorf("expected 100 entries, got %d", n)
+	}
+	select {
+	case <-ran:
+	case <-time.After(OneSecond):
+		t.Error("expected the every-second job to run")
+	}
+}
+
+func BenchmarkAddWhileRunning(b *testing.B) {
+	const n = 1000
+	for i := 0; i < b.N; i++ {
+		cron := New()
+		cron.Start()
+		var wg sync.WaitGroup
+		for j := 0; j < n; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cron.AddFunc("0 0 0 1 1 ?", func() {})
+			}()
+		}
+		wg.Wait()
+		cron.Stop()
+	}
+}
+
+func TestStatsInterval(t *testing.T) {
+	stats := make(chan Stats, 10)
+	cron := New()
+	cron.AddNameFunc("job", "* * * * * ?", func() {})
+	cron.AddFunc("0 0 0 1 1 ?", func() {})
+	cron.SetStatsInterval(200*time.Millisecond, func(st Stats) { stats <- st })
+	cron.Start()
+	defer cron.Stop()
+
+	select {
+	case st := <-stats:
+		if st.Entries != 2 || st.Next.IsZero() {
+			t.Errorf("unexpected stats %+v", st)
+		}
+	case <-time.After(OneSecond):
+		t.Fatal("expected stats to be reported")
+	}
+
+	time.Sleep(OneSecond)
+	if st := cron.Stats(); st.Runs < 1 {
+		t.Errorf("expected at least 1 run, got %+v", st)
+	}
+	if e := cron.Entries()[0]; e.Name != "job" || e.RunCount < 1 {
+		t.Errorf("expected the job's runs to be counted, got %d", e.RunCount)
+	}
+
+	cron.SetStatsInterval(0, nil)
+	time.Sleep(50 * time.Millisecond) // let calls already started deliver
+	for len(stats) > 0 {
+		<-stats
+	}
+	select {
+	case <-stats:
+		t.Error("expected no stats once disabled")
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	for _, start := range []bool{false, true} {
+		ran := make(chan struct{}, 10)
+		cron := New()
+		if start {
+			cron.Start()
+		}
+		cron.Submit("task", func() { ran <- struct{}{} })
+
+		select {
+		case <-ran:
+		case <-time.After(OneSecond):
+			t.Fatal("expected the submitted task to run")
+		}
+		select {
+		case <-ran:
+			t.Error("expected the submitted task to run once")
+		case <-time.After(100 * time.Millisecond):
+		}
+		if n := len(cron.Entries()); n != 0 {
+			t.Errorf("expected no entries to remain, got %d", n)
+		}
+		if st := cron.Stats(); st.Runs != 1 {
+			t.Errorf("expected the run to be counted, got %+v", st)
+		}
+		cron.Stop()
+	}
+}
+
+// Test that a nil location defaults to UTC instead of panicking.
+func TestNilLocation(t *testing.T) {
+	cron := NewWithLocation(nil)
+	if loc := cron.Location(); loc != time.UTC {
+		t.Errorf("expected UTC, got %v", loc)
+	}
+	if now := cron.now(); now.Location() != time.UTC {
+		t.Errorf("expected the time in UTC, got %v", now.Location())
+	}
+
+	cron.location = nil
+	if loc := cron.Location(); loc != time.UTC {
+		t.Errorf("expected UTC for an unset location, got %v", loc)
+	}
+	cron.AddFunc("0 0 0 1 1 ?", func() {})
+	cron.Start()
+	cron.Entries()
+	cron.Stop()
+}
+
+// Test that the delay range passed to AddDelayJob is applied to the entry.
+func TestAddDelayJobDelayRange(t *testing.T) {
+	cron := New()
+	if err := cron.AddDelayJob("0 0 * * * ?", 30, DummyJob{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cron.AddDelayFunc("0 30 * * * ?", 45, func() {}); err != nil {
+		t.Fatal(err)
+	}
+	cron.AddNameJob("plain", "0 15 * * * ?", DummyJob{})
+
+	ranges := map[string]int{}
+	for _, e := range cron.entries {
+		ranges[e.Spec] = e.DelayRange
+	}
+	expected := map[string]int{"0 0 * * * ?": 30, "0 30 * * * ?": 45, "0 15 * * * ?": 0}
+	if !reflect.DeepEqual(ranges, expected) {
+		t.Errorf("expected delay ranges %v, got %v", expected, ranges)
+	}
+}
+
+// Test that an inline job runs synchronously, and that a slow one is logged.
+func TestRunInline(t *testing.T) {
+	var buf syncWriter
+	cron := New()
+	cron.ErrorLog = log.New(&buf, "", 0)
+	ran := 0
+	cron.AddNameFunc("tiny", "* * * * * ?", func() { ran++ })
+	cron.AddNameFunc("slow", "* * * * * ?", func() { time.Sleep(2 * inlineWarnThreshold) })
+	cron.SetRunInline("tiny", true)
+	cron.SetRunInline("slow", true)
+
+	now := time.Now()
+	cron.Tick(now)
+	cron.Tick(now.Add(time.Second))
+
+	if ran != 1 {
+		t.Errorf("expected the inline job to have run when Tick returned, ran %d times", ran)
+	}
+	if s := buf.String(); !strings.Contains(s, `inline job "slow"`) || strings.Contains(s, `"tiny"`) {
+		t.Errorf("expected only the slow inline job to be logged, got %q", s)
+	}
+}
+
+// Test that a job still running from a previous activation is skipped on the
+// following ticks, and runs again once it has returned.
+func TestSkipIfRunning(t *testing.T) {
+	var (
+		buf     syncWriter
+		runs    int32
+		release = make(chan struct{})
+	)
+	cron := New()
+	cron.ErrorLog = log.New(&buf, "", 0)
+	cron.AddFuncSkipIfRunning("slow", "* * * * * ?", func() {
+		atomic.AddInt32(&runs, 1)
+		<-release
+	})
+
+	now := time.Now()
+	cron.Tick(now)
+	for i := 1; i <= 3; i++ {
+		cron.Tick(now.Add(time.Duration(i) * time.Second))
+	}
+	release <- struct{}{}
+	cron.jobs.Wait()
+
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("expected the job to run once while overrunning, ran %d times", n)
+	}
+	e, _ := cron.GetEntry("slow")
+	if e.SkipReasons["running"] != 2 {
+		t.Errorf("expected 2 runs skipped as running, got %v", e.SkipReasons)
+	}
+	if !strings.Contains(buf.String(), "skipped slow") {
+		t.Errorf("expected the skip to be logged, got %q", buf.String())
+	}
+
+	close(release)
+	cron.Tick(now.Add(4 * time.Second))
+	cron.jobs.Wait()
+	if n := atomic.LoadInt32(&runs); n != 2 {
+		t.Errorf("expected the job to run again once finished, ran %d times", n)
+	}
+}
+
+// Test that activations of a slow job in queue mode all run, one after the
+// other, and that activations beyond the queue limit are skipped.
+func TestQueueIfRunning(t *testing.T) {
+	for _, c := range []struct {
+		maxQueued, runs, skipped int
+	}{
+		{0, 3, 0},
+		{1, 2, 1},
+	} {
+		var (
+			runs    int32
+			running int32
+			overlap bool
+			release = make(chan struct{})
+		)
+		cron := New()
+		cron.AddFuncQueueIfRunning("slow", "* * * * * ?", c.maxQueued, func() {
+			if atomic.AddInt32(&running, 1) > 1 {
+				overlap = true
+			}
+			<-release
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&runs, 1)
+		})
+
+		now := time.Now()
+		cron.Tick(now)
+		for i := 1; i <= 3; i++ {
+			cron.Tick(now.Add(time.Duration(i) * time.Second))
+		}
+		close(release)
+		cron.jobs.Wait()
+
+		if overlap {
+			t.Errorf("max %d: expected runs not to overlap", c.maxQueued)
+		}
+		if n := int(atomic.LoadInt32(&runs)); n != c.runs {
+			t.Errorf("max %d: expected %d runs, got %d", c.maxQueued, c.runs, n)
+		}
+		e, _ := cron.GetEntry("slow")
+		if e.RunCount != c.runs || e.SkipReasons["queue full"] != c.skipped {
+			t.Errorf("max %d: expected %d runs and %d skipped, got %d and %v",
+				c.maxQueued, c.runs, c.skipped, e.RunCount, e.SkipReasons)
+		}
+	}
+}
+
+// Test that schedules firing more often than the minimum interval are rejected.
+func TestSetMinInterval(t *testing.T) {
+	cron := New()
+	cron.SetMinInterval(time.Minute)
+	if err := cron.AddFunc("* * * * * ?", func() {}); err != ErrTooFrequent {
+		t.Errorf("expected ErrTooFrequent for an every-second spec, got %v", err)
+	}
+	if err := cron.AddFunc("0 0,1 9 * * *", func() {}); err != nil {
+		t.Errorf("expected a spec firing once a minute to be accepted, got %v", err)
+	}
+	if err := cron.AddFunc("0,30 0 9 * * *", func() {}); err != ErrTooFrequent {
+		t.Errorf("expected ErrTooFrequent for runs 30s apart, got %v", err)
+	}
+	cron.Schedule(Every(time.Second), FuncJob(func() {}))
+	if n := len(cron.Entries()); n != 1 {
+		t.Errorf("expected only the accepted entry to be added, got %d entries", n)
+	}
+
+	cron.SetMinInterval(0)
+	if err := cron.AddFunc("* * * * * ?", func() {}); err != nil {
+		t.Errorf("expected no limit after clearing the minimum, got %v", err)
+	}
+}
+
+// Test that a job added disabled is listed but doesn't run until resumed.
+func TestAddFuncDisabled(t *testing.T) {
+	var runs int32
+	cron := New()
+	if err := cron.AddFuncDisabled("approval", "* * * * * ?", func() { atomic.AddInt32(&runs, 1) }); err != nil {
+		t.Fatal(err)
+	}
+	if entries := cron.Entries(); len(entries) != 1 || !entries[0].Paused {
+		t.Fatalf("expected one paused entry, got %v", entries)
+	}
+
+	now := time.Now()
+	cron.Tick(now)
+	cron.Tick(now.Add(time.Second))
+	cron.jobs.Wait()
+	if n := atomic.LoadInt32(&runs); n != 0 {
+		t.Fatalf("expected the disabled job not to run, ran %d times", n)
+	}
+
+	if err := cron.Resume("approval"); err != nil {
+		t.Fatal(err)
+	}
+	cron.Tick(now.Add(2 * time.Second))
+	cron.jobs.Wait()
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("expected the job to run once resumed, ran %d times", n)
+	}
+}
+
+// fakeClock is a Clock that only moves when told to.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
+
+// Test that the scheduler runs jobs by the injected clock: not before it
+// reaches the activation, and at the activation in fake time once it does.
+func TestSetClock(t *testing.T) {
+	start := time.Date(2030, 1, 1, 11, 59, 59, 950e6, time.UTC)
+	clk := &fakeClock{now: start}
+	ran := make(chan struct{}, 1)
+	cron := NewWithLocation(time.UTC)
+	cron.SetClock(clk)
+	cron.AddNameFunc("noon", "0 0 12 * * ?", func() { ran <- struct{}{} })
+	cron.Start()
+	defer cron.Stop()
+
+	select {
+	case <-ran:
+		t.Fatal("expected the job not to run before the clock reaches noon")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	noon := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	clk.Set(noon)
+	select {
+	case <-ran:
+	case <-time.After(OneSecond):
+		t.Fatal("expected the job to run once the clock reaches noon")
+	}
+	if e, _ := cron.GetEntry("noon"); !e.Prev.Equal(noon) || !e.Next.Equal(noon.AddDate(0, 0, 1)) {
+		t.Errorf("expected the run at %v in fake time, got Prev %v and Next %v", noon, e.Prev, e.Next)
+	}
+}
+
+// Test that Stop cancels the context of a running context-aware job, and that
+// jobs started after a restart get a live context.
+func TestAddContextFunc(t *testing.T) {
+	started := make(chan context.Context, 1)
+	cron := New()
+	cron.AddContextFunc("wait", "* * * * * ?", func(ctx context.Context) {
+		select {
+		case started <- ctx:
+		default:
+		}
+		<-ctx.Done()
+	})
+	cron.Start()
+
+	var ctx context.Context
+	select {
+	case ctx = <-started:
+	case <-time.After(OneSecond):
+		t.Fatal("expected the job to start")
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("expected a live context while running, got %v", ctx.Err())
+	}
+	if err := cron.StopAndWait(OneSecond); err != nil {
+		t.Fatalf("expected Stop to unblock the job, got %v", err)
+	}
+	if ctx.Err() != context.Canceled {
+		t.Errorf("expected the context to be cancelled, got %v", ctx.Err())
+	}
+
+	cron.Start()
+	defer cron.Stop()
+	select {
+	case ctx = <-started:
+	case <-time.After(OneSecond):
+		t.Fatal("expected the job to start again")
+	}
+	if ctx.Err() != nil {
+		t.Errorf("expected a live context after a restart, got %v", ctx.Err())
+	}
+}
+
+// Test that a second entry with a taken name is rejected, before and after
+// the Cron is started, and that the first one is kept.
+func TestDuplicateName(t *testing.T) {
+	cron := New()
+	if err := cron.AddNameFunc("job", "@every 1h", func() {}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cron.AddNameFunc("job", "@every 2h", func() {}); err != ErrDuplicateName {
+		t.Errorf("expected ErrDuplicateName before start, got %v", err)
+	}
+	if err := cron.NameAndDelaySchedule("job", Every(time.Hour), 0, FuncJob(func() {})); err != ErrDuplicateName {
+		t.Errorf("expected ErrDuplicateName from NameAndDelaySchedule before start, got %v", err)
+	}
+	cron.AddFunc("@every 1h", func() {})
+	if err := cron.AddFunc("@every 1h", func() {}); err != nil {
+		t.Errorf("expected unnamed entries to be allowed, got %v", err)
+	}
+
+	cron.Start()
+	defer cron.Stop()
+	if err := cron.AddNameFunc("job", "@every 2h", func() {}); err != ErrDuplicateName {
+		t.Errorf("expected ErrDuplicateName while running, got %v", err)
+	}
+	if err := cron.NameAndDelaySchedule("job", Every(time.Hour), 0, FuncJob(func() {})); err != ErrDuplicateName {
+		t.Errorf("expected ErrDuplicateName from NameAndDelaySchedule while running, got %v", err)
+	}
+	if n := cron.EntryCount(); n != 3 {
+		t.Errorf("expected 3 entries, got %d", n)
+	}
+	if e, _ := cron.GetEntry("job"); e.Spec != "@every 1h" {
+		t.Errorf("expected the first entry to be kept, got %q", e.Spec)
+	}
+
+	cron.RemoveJob("job")
+	if err := cron.AddNameFunc("job", "@every 2h", func() {}); err != nil {
+		t.Errorf("expected the name to be free once removed, got %v", err)
+	}
+}
+
+// Test that updating the schedule of a running entry moves its next run to
+// the new spec, keeping its history.
+func TestUpdateSchedule(t *testing.T) {
+	ran := make(chan struct{}, 10)
+	cron := New()
+	cron.AddNameFunc("job", "0 0 0 1 1 ?", func() { ran <- struct{}{} })
+	cron.Start()
+	defer cron.Stop()
+
+	if err := cron.UpdateSchedule("job", "* * * * * ?"); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := cron.GetEntry("job")
+	if e.Spec != "* * * * * ?" || e.Next.Sub(time.Now()) > time.Second {
+		t.Errorf("expected the next run within a second, got %v", e.Next)
+	}
+	select {
+	case <-ran:
+	case <-time.After(OneSecond):
+		t.Fatal("expected the job to run on its new schedule")
+	}
+
+	if err := cron.UpdateSchedule("job", "0 0 0 1 1 ?"); err != nil {
+		t.Fatal(err)
+	}
+	e, _ = cron.GetEntry("job")
+	if e.Next.Month() != time.January || e.Next.Day() != 1 || e.Prev.IsZero() || e.RunCount == 0 {
+		t.Errorf("expected the update to keep the history and move the next run, got %+v", e)
+	}
+
+	if err := cron.UpdateSchedule("job", "* * *"); err == nil {
+		t.Error("expected an error for an invalid spec")
+	}
+	if err := cron.UpdateSchedule("missing", "* * * * * ?"); err == nil {
+		t.Error("expected an error for an unknown name")
+	}
+}
+
+// Test that the job hooks fire around each run with the entry name, the
+// duration of the run and the value recovered from a panic, with its stack.
+func TestJobHooks(t *testing.T) {
+	type run struct {
+		start     time.Time
+		dur       time.Duration
+		recovered interface{}
+		stack     []byte
+	}
+	var (
+		mu       sync.Mutex
+		started  = map[string]time.Time{}
+		complete = map[string]run{}
+	)
+	cron := New()
+	cron.ErrorLog = log.New(&syncWriter{}, "", 0)
+	cron.OnJobStart = func(name string, t time.Time) {
+		mu.Lock()
+		started[name] = t
+		mu.Unlock()
+	}
+	cron.OnJobComplete = func(name string, t time.Time, dur time.Duration, recovered interface{}, stack []byte) {
+		mu.Lock()
+		complete[name] = run{t, dur, recovered, stack}
+		mu.Unlock()
+	}
+	cron.AddNameFunc("slow", "* * * * * ?", func() { time.Sleep(20 * time.Millisecond) })
+	cron.AddNameFunc("boom", "* * * * * ?", func() { panic("boom") })
+
+	now := time.Now()
+	cron.Tick(now)
+	cron.Tick(now.Add(time.Second))
+	cron.jobs.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for name, t0 := range started {
+		if !complete[name].start.Equal(t0) {
+			t.Errorf("%s: expected the same start time in both hooks, got %v and %v", name, t0, complete[name].start)
+		}
+	}
+	if len(started) != 2 {
+		t.Errorf("expected both jobs to be reported started, got %v", started)
+	}
+	if r, ok := complete["slow"]; !ok || r.dur < 20*time.Millisecond || r.dur > OneSecond || r.recovered != nil || r.stack != nil {
+		t.Errorf("expected slow to complete in about 20ms without panicking, got %+v", r)
+	}
+	if r, ok := complete["boom"]; !ok || r.recovered != "boom" || !strings.Contains(string(r.stack), "TestJobHooks") {
+		t.Errorf("expected boom to complete with the recovered panic and its stack, got %+v", r)
+	}
+}
+
+// Test that Crons given identically seeded sources compute the same delayed
+// activations, and that the delays stay within range.
+func TestSetRand(t *testing.T) {
+	hourly, _ := Parse("0 0 * * * ?")
+	quarters, _ := Parse("0 15,45 * * * ?")
+	newCron := func(seed int64) *Cron {
+		cron := NewWithLocation(time.UTC)
+		cron.SetRand(rand.New(rand.NewSource(seed)))
+		cron.NameAndDelaySchedule("spec", hourly, 900, FuncJob(func() {}))
+		cron.NameAndDelaySchedule("union", Union(hourly, quarters), 600, FuncJob(func() {}))
+		return cron
+	}
+	a, b := newCron(7), newCron(7)
+
+	now := time.Date(2024, 1, 1, 0, 30, 0, 0, time.UTC)
+	for i := 0; i < 24; i++ {
+		a.Tick(now)
+		b.Tick(now)
+		for _, name := range []string{"spec", "union"} {
+			x, _ := a.GetEntry(name)
+			y, _ := b.GetEntry(name)
+			if !x.Next.Equal(y.Next) {
+				t.Fatalf("%s: expected the same delayed next time, got %v and %v", name, x.Next, y.Next)
+			}
+			if d := x.Next.Sub(x.BaseNext); d < 0 || d >= time.Duration(x.DelayRange)*time.Second {
+				t.Errorf("%s: delay %v out of range", name, d)
+			}
+		}
+		now = now.Add(time.Hour)
+	}
+	a.jobs.Wait()
+	b.jobs.Wait()
+}
+
+// Test that RemoveJob reports whether it removed anything, stopped and
+// running.
+func TestRemoveJobResult(t *testing.T) {
+	cron := New()
+	cron.AddNameFunc("a", "@every 1h", func() {})
+	cron.AddNameFunc("b", "@every 1h", func() {})
+	if !cron.RemoveJob("a") {
+		t.Error("expected a to be removed")
+	}
+	if cron.RemoveJob("a") {
+		t.Error("expected nothing to remove the second time")
+	}
+
+	cron.Start()
+	defer cron.Stop()
+	if !cron.RemoveJob("b") {
+		t.Error("expected b to be removed while running")
+	}
+	if cron.RemoveJob("missing") {
+		t.Error("expected nothing to remove for an unknown name")
+	}
+}
+
+// countJob is a comparable Job that counts its runs.
+type countJob struct{ runs int32 }
+
+func (j *countJob) Run() { atomic.AddInt32(&j.runs, 1) }
+
+// Test that EntriesForJob finds every entry registered with the same job, in
+// the order they were added, and nothing for jobs that cannot be compared.
+func TestEntriesForJob(t *testing.T) {
+	shared, other := &countJob{}, &countJob{}
+	cron := New()
+	cron.AddNameJob("hourly", "0 0 * * * ?", shared)
+	cron.AddNameJob("other", "0 0 * * * ?", other)
+	cron.AddNameJob("daily", "0 0 0 * * ?", shared)
+	fn := FuncJob(func() {})
+	cron.AddNameJob("func", "0 0 0 * * ?", fn)
+	cron.AddNameErrorFunc("error", "0 0 0 * * ?", func() error { return nil })
+
+	entries := cron.EntriesForJob(shared)
+	if len(entries) != 2 || entries[0].Name != "hourly" || entries[1].Name != "daily" {
+		t.Errorf("expected hourly and daily, got %v", entries)
+	}
+	if entries := cron.EntriesForJob(fn); len(entries) != 0 {
+		t.Errorf("expected no entry for an uncomparable job, got %v", entries)
+	}
+	if entries := cron.EntriesForJob(&countJob{}); len(entries) != 0 {
+		t.Errorf("expected no entry for an unregistered job, got %v", entries)
+	}
+}
+
+// Test that an entry whose schedule returns a time already past runs once,
+// rather than being due again at once and spinning the scheduler.
+func TestPastDueRunsOnce(t *testing.T) {
+	job := &countJob{}
+	var buf syncWriter
+	cron := New()
+	cron.ErrorLog = log.New(&buf, "", 0)
+	cron.NameAndDelaySchedule("past", stuckSchedule{time.Now().Add(-time.Hour)}, 0, job)
+	cron.Start()
+	defer cron.Stop()
+
+	time.Sleep(200 * time.Millisecond)
+	cron.jobs.Wait()
+	if runs := atomic.LoadInt32(&job.runs); runs != 1 {
+		t.Errorf("expected 1 run, got %d", runs)
+	}
+	if e, ok := cron.GetEntry("past"); !ok || !e.Next.IsZero() {
+		t.Errorf("expected the entry to be kept, never to run again, got %v", e)
+	}
+	if !strings.Contains(buf.String(), "does not advance") {
+		t.Errorf("expected the stuck schedule to be logged, got %q", buf.String())
+	}
+}
+
+// Test that the delay range of every kind of add is checked against the
+// maximum, which defaults to a day.
+func TestSetMaxDelay(t *testing.T) {
+	cron := New()
+	if err := cron.AddDelayFunc("@hourly", DefaultMaxDelay, func() {}); err != nil {
+		t.Errorf("expected the default maximum to be accepted, got %v", err)
+	}
+	err := cron.AddDelayFunc("@hourly", DefaultMaxDelay+1, func() {})
+	if err == nil || !strings.Contains(err.Error(), "0-86400 seconds") {
+		t.Errorf("expected an error naming the limit, got %v", err)
+	}
+	if err := cron.AddDelayJob("@hourly", -1, FuncJob(func() {})); err == nil {
+		t.Error("expected an error for a negative delay range")
+	}
+
+	cron.SetMaxDelay(600)
+	err = cron.AddDelayJob("@hourly", 601, FuncJob(func() {}))
+	if err == nil || !strings.Contains(err.Error(), "0-600 seconds") {
+		t.Errorf("expected an error naming the new limit, got %v", err)
+	}
+	if err := cron.AddDelayJob("@hourly", 600, FuncJob(func() {})); err != nil {
+		t.Errorf("expected the new maximum to be accepted, got %v", err)
+	}
+
+	hourly, _ := Parse("@hourly")
+	if err := cron.NameAndDelaySchedule("wide", hourly, 601, FuncJob(func() {})); err == nil {
+		t.Error("expected an error from NameAndDelaySchedule above the maximum")
+	}
+	cron.NameAndDelaySchedule("narrow", hourly, 60, FuncJob(func() {}))
+	if _, ok := cron.GetEntry("wide"); ok {
+		t.Error("expected an entry above the maximum not to be added")
+	}
+	if e, ok := cron.GetEntry("narrow"); !ok || e.DelayRange != 60 {
+		t.Errorf("expected narrow to be added with its delay range, got %v", e)
+	}
+	if n := cron.EntryCount(); n != 3 {
+		t.Errorf("expected 3 entries, got %d", n)
+	}
+
+	cron = New()
+	cron.SetMaxDelay(2 * DefaultMaxDelay)
+	if err := cron.AddDelayFunc("@daily", 2*DefaultMaxDelay, func() {}); err != nil {
+		t.Errorf("expected a raised maximum to be accepted, got %v", err)
+	}
+}
+
+// Test that EntriesJSON summarizes the entries of a running Cron in plain
+// values that marshal to JSON.
+func TestEntriesJSON(t *testing.T) {
+	cron := New()
+	cron.AddNameFunc("report", "0 0-30/15 10 * * 1-5", func() {})
+	every, _ := Parse("@every 1h")
+	cron.NameAndDelaySchedule("sync", every, 30, FuncJob(func() {}))
+	now := time.Date(2012, time.July, 9, 8, 0, 0, 0, time.Local)
+	cron.Tick(now)
+
+	summaries, err := cron.EntriesJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(summaries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []EntrySummary
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 summaries, got %s", data)
+	}
+	report, sync := got[1], got[0]
+	if sync.Name != "sync" || sync.Schedule != "@every 1h0m0s" || sync.DelayRange != 30 ||
+		!sync.Next.Equal(now.Add(time.Hour)) || !sync.Prev.IsZero() {
+		t.Errorf("unexpected summary of sync: %+v", sync)
+	}
+	if report.Name != "report" || report.Schedule != "0 0-30/15 10 * * 1-5" || report.DelayRange != 0 ||
+		!report.Next.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("unexpected summary of report: %+v", report)
+	}
+
+	// Built-in schedules describe themselves, and a schedule that cannot does
+	// not keep the other entries from being summarized.
+	daily, _ := Parse("0 0 9 * * ?")
+	times, _ := TimesOfDay("0 9", "30 17")
+	at := time.Date(2012, time.December, 1, 9, 0, 0, 0, time.UTC)
+	anchor := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	expected := map[string]string{
+		"report":   "0 0-30/15 10 * * 1-5",
+		"sync":     "@every 1h0m0s",
+		"once":     "at 2012-12-01T09:00:00Z",
+		"december": "0 0 9 * * * from 2012-12-01T00:00:00Z until 2012-12-31T23:59:59Z",
+		"union":    "0 0 9 * * * | 0 30 17 * * *",
+		"biweekly": "every 2 weeks on Monday at 09:30 from 2024-01-01",
+		"business": "business day 3 of every month at 09:00",
+		"sunset":   "sunset+30m0s at 48.85,2.35",
+		"custom":   "<custom>",
+	}
+	job := FuncJob(func() {})
+	cron.NameAndDelaySchedule("once", At(at), 0, job)
+	cron.NameAndDelaySchedule("december", Between(daily, at.Add(-9*time.Hour), at.AddDate(0, 0, 31).Add(-9*time.Hour-time.Second)), 0, job)
+	cron.NameAndDelaySchedule("union", times, 0, job)
+	cron.NameAndDelaySchedule("biweekly", BiWeekly(anchor, time.Monday, 9, 30), 0, job)
+	cron.NameAndDelaySchedule("business", BusinessDayOfMonth(3, 9, 0), 0, job)
+	cron.NameAndDelaySchedule("sunset", Solar(48.85, 2.35, Sunset, 30*time.Minute), 0, job)
+	cron.NameAndDelaySchedule("custom", &ZeroSchedule{}, 0, job)
+	summaries, err = cron.EntriesJSON()
+	if err != nil || len(summaries) != len(expected) {
+		t.Fatalf("expected %d summaries, got %v, %v", len(expected), summaries, err)
+	}
+	for _, s := range summaries {
+		if s.Schedule != expected[s.Name] {
+			t.Errorf("%s: expected schedule %q, got %q", s.Name, expected[s.Name], s.Schedule)
+		}
+	}
+}
+
+// Test that entries due at the same time, or not scheduled yet, are listed
+// in the order they were added, before and after Start.
+func TestEntriesOrder(t *testing.T) {
+	cron := New()
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		cron.AddNameFunc(name, "0 0 0 * * ?", func() {})
+	}
+	cron.RemoveJob("a")
+	names := func() []string {
+		var names []string
+		for _, e := range cron.Entries() {
+			names = append(names, e.Name)
+		}
+		return names
+	}
+	expected := []string{"b", "c", "d", "e"}
+	if actual := names(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v before Start, got %v", expected, actual)
+	}
+
+	cron.Start()
+	defer cron.Stop()
+	if actual := names(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v while running, got %v", expected, actual)
+	}
+}
+
+// Test that EntryCount follows adds and removals, stopped and running.
+func TestEntryCount(t *testing.T) {
+	cron := New()
+	if n := cron.EntryCount(); n != 0 {
+		t.Errorf("expected no entries, got %d", n)
+	}
+	cron.AddNameFunc("a", "@every 1h", func() {})
+	cron.AddNameFunc("b", "@every 1h", func() {})
+	if n := cron.EntryCount(); n != 2 {
+		t.Errorf("expected 2 entries before start, got %d", n)
+	}
+
+	cron.Start()
+	defer cron.Stop()
+	cron.AddNameFunc("c", "@every 1h", func() {})
+	cron.RemoveJob("a")
+	if n := cron.EntryCount(); n != 2 {
+		t.Errorf("expected 2 entries while running, got %d", n)
+	}
+}
+
+// Test that NextN previews consecutive activations without delay.
+func TestNextN(t *testing.T) {
+	cron := New()
+	hourly, _ := Parse("0 0 * * * ?")
+	cron.NameAndDelaySchedule("hourly", hourly, 600, FuncJob(func() {}))
+	cron.AddNameFunc("never", "0 0 0 30 2 ?", func() {})
+
+	times := cron.NextN("hourly", 5)
+	if len(times) != 5 {
+		t.Fatalf("expected 5 times, got %v", times)
+	}
+	if !times[0].After(time.Now()) || times[0].Minute() != 0 || times[0].Second() != 0 {
+		t.Errorf("expected the first time to be the next hour, got %v", times[0])
+	}
+	for i := 1; i < len(times); i++ {
+		if d := times[i].Sub(times[i-1]); d != time.Hour {
+			t.Errorf("expected times an hour apart, got %v between %v and %v", d, times[i-1], times[i])
+		}
+	}
+
+	if times := cron.NextN("never", 5); times != nil {
+		t.Errorf("expected nil for an unsatisfiable schedule, got %v", times)
+	}
+	if times := cron.NextN("missing", 5); times != nil {
+		t.Errorf("expected nil for an unknown name, got %v", times)
+	}
+}
+
+// Test that a failing ErrorJob is retried from the retry queue with a doubling
+// backoff until it succeeds, and that Stop drops the retries still pending.
+func TestRetries(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+	start := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	clk := &fakeClock{now: start}
+	cron := NewWithLocation(time.UTC)
+	cron.SetClock(clk)
+	cron.ErrorLog = log.New(&syncWriter{}, "", 0)
+	cron.AddNameErrorFunc("flaky", "* * * * * ?", func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		if times = append(times, clk.Now()); len(times) < 3 {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	if err := cron.SetRetries("flaky", 5, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	tick := func(d time.Duration) int {
+		clk.Set(start.Add(d))
+		cron.Tick(start.Add(d))
+		cron.jobs.Wait()
+		mu.Lock()
+		defer mu.Unlock()
+		return len(times)
+	}
+
+	tick(0)
+	if n := tick(time.Second); n != 1 {
+		t.Fatalf("expected the job to run once, ran %d", n)
+	}
+	if n := cron.PendingRetries(); n != 1 {
+		t.Errorf("expected 1 pending retry, got %d", n)
+	}
+	// The retries wait 20ms, then 40ms.
+	for _, c := range []struct {
+		at   time.Duration
+		runs int
+	}{
+		{time.Second + 19*time.Millisecond, 1},
+		{time.Second + 20*time.Millisecond, 2},
+		{time.Second + 59*time.Millisecond, 2},
+		{time.Second + 60*time.Millisecond, 3},
+	} {
+		if n := tick(c.at); n != c.runs {
+			t.Fatalf("expected %d runs at %v, got %d", c.runs, c.at, n)
+		}
+	}
+	if n := cron.PendingRetries(); n != 0 {
+		t.Errorf("expected no pending retry once the job succeeds, got %d", n)
+	}
+
+	var runs int32
+	stopping := New()
+	stopping.ErrorLog = log.New(&syncWriter{}, "", 0)
+	stopping.AddNameErrorFunc("down", "* * * * * ?", func() error {
+		atomic.AddInt32(&runs, 1)
+		return errors.New("down")
+	})
+	stopping.SetRetries("down", 3, time.Hour)
+	stopping.Start()
+	for stopping.PendingRetries() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := stopping.StopAndWait(OneSecond); err != nil {
+		t.Errorf("expected Stop to abandon the retries, got %v", err)
+	}
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("expected no retry after Stop, ran %d times", n)
+	}
+	if n := stopping.PendingRetries(); n != 0 {
+		t.Errorf("expected Stop to drop the pending retries, got %d", n)
+	}
+}
+
+// Test that a job that is running when the Cron stops, and fails after, is
+// not retried: its retries belong to the jobs cancelled by Stop.
+func TestRetriesAfterStop(t *testing.T) {
+	var runs int32
+	started, release := make(chan struct{}), make(chan struct{})
+	cron := New()
+	cron.ErrorLog = log.New(&syncWriter{}, "", 0)
+	cron.AddNameErrorFunc("slow", "* * * * * ?", func() error {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			close(started)
+			<-release
+		}
+		return errors.New("down")
+	})
+	cron.SetRetries("slow", 3, 10*time.Millisecond)
+
+	cron.Start()
+	<-started
+	cron.Stop()
+	close(release)
+	cron.jobs.Wait()
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("expected no retry after Stop, ran %d times", n)
+	}
+}
+
+// Test that no more than the maximum number of jobs run at once, and that the
+// jobs over the limit wait rather than being dropped.
+func TestSetMaxConcurrent(t *testing.T) {
+	const limit, jobs = 2, 6
+	var running, peak, runs int32
+	cron := New()
+	cron.SetMaxConcurrent(limit)
+	for i := 0; i < jobs; i++ {
+		cron.AddNameFunc(fmt.Sprint("slow", i), "* * * * * ?", func() {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&runs, 1)
+		})
+	}
+
+	now := time.Now()
+	cron.Tick(now)
+	cron.Tick(now.Add(time.Second))
+	cron.jobs.Wait()
+	if p := atomic.LoadInt32(&peak); p != limit {
+		t.Errorf("expected at most %d jobs at once, reaching it, got %d", limit, p)
+	}
+	if n := atomic.LoadInt32(&runs); n != jobs {
+		t.Errorf("expected all %d jobs to run, ran %d", jobs, n)
+	}
+}
+
+// Test that TriggerNow runs the job out of band, leaving its schedule alone.
+func TestTriggerNow(t *testing.T) {
+	var runs int32
+	cron := New()
+	cron.AddNameFunc("report", "0 0 0 1 1 ?", func() { atomic.AddInt32(&runs, 1) })
+	cron.Start()
+	defer cron.Stop()
+
+	before, _ := cron.GetEntry("report")
+	if err := cron.TriggerNow("report"); err != nil {
+		t.Fatal(err)
+	}
+	cron.jobs.Wait()
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("expected the job to run once, ran %d times", n)
+	}
+	after, _ := cron.GetEntry("report")
+	if !after.Next.Equal(before.Next) || !after.Prev.IsZero() || after.RunCount != 0 {
+		t.Errorf("expected the schedule to be left alone, got %+v", after)
+	}
+
+	if err := cron.TriggerNow("missing"); err == nil {
+		t.Error("expected an error triggering an unknown name")
+	}
+}
+
+// Test that a paused entry is listed but skipped, keeps its schedule moving,
+// and runs again once resumed.
+func TestPauseResume(t *testing.T) {
+	var runs int32
+	cron := New()
+	cron.AddNameFunc("job", "* * * * * ?", func() { atomic.AddInt32(&runs, 1) })
+
+	now := time.Now()
+	cron.Tick(now)
+	if err := cron.Pause("job"); err != nil {
+		t.Fatal(err)
+	}
+	cron.Tick(now.Add(time.Second))
+	cron.jobs.Wait()
+	if n := atomic.LoadInt32(&runs); n != 0 {
+		t.Errorf("expected the paused job not to run, ran %d times", n)
+	}
+	entries := cron.Entries()
+	if len(entries) != 1 || !entries[0].Paused || !entries[0].Next.After(now.Add(time.Second)) {
+		t.Errorf("expected the paused entry listed with its next run moved on, got %v", entries)
+	}
+
+	if err := cron.Resume("job"); err != nil {
+		t.Fatal(err)
+	}
+	cron.Tick(now.Add(2 * time.Second))
+	cron.jobs.Wait()
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("expected the job to run once resumed, ran %d times", n)
+	}
+
+	if err := cron.Pause("missing"); err == nil {
+		t.Error("expected an error pausing an unknown name")
+	}
+	if err := cron.Resume(""); err == nil {
+		t.Error("expected an error resuming an empty name")
+	}
+}
+
+// Test that PauseWhere stops only the matching entries from running, and
+// ResumeWhere lets them run again.
+func TestPauseWhere(t *testing.T) {
+	var billing, search int32
+	cron := New()
+	cron.AddNameFunc("billing.invoices", "* * * * * ?", func() { atomic.AddInt32(&billing, 1) })
+	cron.AddNameFunc("billing.reminders", "* * * * * ?", func() { atomic.AddInt32(&billing, 1) })
+	cron.AddNameFunc("search.reindex", "* * * * * ?", func() { atomic.AddInt32(&search, 1) })
+	isBilling := func(e *Entry) bool { return strings.HasPrefix(e.Name, "billing.") }
+
+	now := time.Now()
+	cron.Tick(now)
+	if n := cron.PauseWhere(isBilling); n != 2 {
+		t.Errorf("expected 2 entries paused, got %d", n)
+	}
+	cron.Tick(now.Add(time.Second))
+	cron.jobs.Wait()
+	if b, s := atomic.LoadInt32(&billing), atomic.LoadInt32(&search); b != 0 || s != 1 {
+		t.Errorf("expected only search to run while billing is paused, got %d and %d runs", b, s)
+	}
+
+	if n := cron.ResumeWhere(isBilling); n != 2 {
+		t.Errorf("expected 2 entries resumed, got %d", n)
+	}
+	cron.Tick(now.Add(2 * time.Second))
+	cron.jobs.Wait()
+	if b := atomic.LoadInt32(&billing); b != 2 {
+		t.Errorf("expected both billing entries to run once resumed, ran %d times", b)
+	}
+}
+
+// Test that snapshots of a running Cron preserve the delay range.
+func TestSnapshotDelayRange(t *testing.T) {
+	cron := New()
+	cron.AddDelayFunc("0 0 * * * ?", 120, func() {})
+	cron.Start()
+	defer cron.Stop()
+
+	if e := cron.Entries()[0]; e.DelayRange != 120 {
+		t.Errorf("expected delay range 120 in the snapshot, got %d", e.DelayRange)
+	}
+}
+
+func TestGetEntry(t *testing.T) {
+	cron := New()
+	cron.AddNameFunc("job", "* * * * * ?", func() {})
+	cron.AddNameFunc("other", "0 0 0 1 1 ?", func() {})
+	if _, ok := cron.GetEntry("missing"); ok {
+		t.Error("expected no entry for an unknown name")
+	}
+
+	cron.Start()
+	defer cron.Stop()
+	<-time.After(OneSecond)
+
+	e, ok := cron.GetEntry("job")
+	if !ok || e.Name != "job" || e.Prev.IsZero() || !e.Next.After(e.Prev) {
+		t.Fatalf("unexpected entry %+v (ok %v)", e, ok)
+	}
+	e.Next = time.Time{}
+	if again, _ := cron.GetEntry("job"); again.Next.IsZero() {
+		t.Error("expected the entry to be a copy")
+	}
+}
+
+func TestResetStats(t *testing.T) {
+	cron := New()
+	cron.AddNameFunc("job", "* * * * * ?", func() {})
+	cron.Start()
+	defer cron.Stop()
+	<-time.After(OneSecond)
+	cron.do(func() { cron.skip(cron.entries[0], "overlap") })
+
+	if !cron.ResetStats("job") {
+		t.Fatal("expected the entry to be found")
+	}
+	e, _ := cron.GetEntry("job")
+	if e.RunCount != 0 || e.SkipCount != 0 || e.SkipReasons != nil {
+		t.Errorf("expected the counters to be reset, got %+v", e)
+	}
+	if e.Prev.IsZero() || e.Next.IsZero() {
+		t.Errorf("expected the schedule to be kept, got %+v", e)
+	}
+
+	<-time.After(OneSecond)
+	if e, _ := cron.GetEntry("job"); e.RunCount < 1 {
+		t.Errorf("expected runs to be counted again after the reset, got %d", e.RunCount)
+	}
+	if cron.ResetStats("missing") {
+		t.Error("expected false for an unknown name")
+	}
+}
+
+// Test driving the Cron manually with Tick.
+func TestTick(t *testing.T) {
+	var ran []string
+	cron := New()
+	cron.SetDispatcher(func(job func()) { job() })
+	cron.AddNameFunc("secondly", "* * * * * ?", func() { ran = append(ran, "secondly") })
+	cron.AddNameFunc("minutely", "0 * * * * ?", func() { ran = append(ran, "minutely") })
+
+	start := time.Date(2012, time.July, 9, 14, 59, 58, 0, time.Local)
+	cron.Tick(start)
+	if len(ran) != 0 {
+		t.Fatalf("expected the first tick only to schedule, got %v", ran)
+	}
+	cron.Tick(start.Add(time.Second))
+	if expected := []string{"secondly"}; !reflect.DeepEqual(ran, expected) {
+		t.Errorf("expected %v, got %v", expected, ran)
+	}
+
+	ran = nil
+	cron.AddNameFunc("late", "* * * * * ?", func() { ran = append(ran, "late") })
+	cron.Tick(start.Add(2 * time.Second))
+	sort.Strings(ran)
+	if expected := []string{"late", "minutely", "secondly"}; !reflect.DeepEqual(ran, expected) {
+		t.Errorf("expected %v, got %v", expected, ran)
+	}
+
+	// Missed activations are not caught up.
+	ran = nil
+	cron.Tick(start.Add(time.Hour))
+	if len(ran) != 3 {
+		t.Errorf("expected each entry to run once after a gap, got %v", ran)
+	}
+}
@@ -38,6 +38,9 @@ A cron expression represents a set of times, using 6 space-separated fields.
 Note: Month and Day-of-week field values are case insensitive.  "SUN", "Sun",
 and "sun" are equally accepted.
 
+A parser created with the SecondOptional option also accepts standard 5-field
+expressions, which start with the minutes and run at second 0.
+
 Special Characters
 
 Asterisk ( * )
@@ -70,6 +73,40 @@ Question mark ( ? )
 Question mark may be used instead of '*' for leaving either day-of-month or
 day-of-week blank.
 
+Day-of-month and day-of-week
+
+When both day fields are restricted, the expression matches days that satisfy
+either of them, as in standard cron: "0 0 0 13 * FRI" runs on the 13th and on
+every Friday. A parser created with the DayAnd option requires both instead,
+so the same expression runs only on Friday the 13th.
+
+In the day-of-month field, 'L' matches the last day of the month, whichever
+day that is: "0 0 0 L * ?" runs at midnight on January 31, February 28 or 29,
+April 30 and so on. It may be listed with other days, as in "1,L".
+
+A day of the month followed by 'W' matches the weekday nearest to it, without
+leaving the month: "0 0 9 15W * ?" runs on the 15th, or on Friday the 14th if
+the 15th is a Saturday, or on Monday the 16th if it is a Sunday. "1W" on a
+Saturday the 1st runs on Monday the 3rd instead of the last day of the
+previous month.
+
+In the day-of-week field, a day followed by 'L' matches the last occurrence of
+that day in the month: "0 0 18 * * 5L" (or "FRIL") runs at 18:00 on the last
+Friday of every month. Days are numbered from 0 for Sunday, as elsewhere in
+the field.
+
+A day of the week followed by '#' and a number from 1 to 5 matches that
+occurrence of the day in the month: "0 0 9 ? * TUE#2" (or "2#2") runs on the
+second Tuesday of every month. Months without such an occurrence, such as a
+fifth Monday, are skipped.
+
+Comments
+
+A parser created with the Comments option ignores a trailing comment, so specs
+can be copied from crontab files as is, e.g. "0 9 * * 1-5 # weekday mornings".
+A comment starts with a '#' at the beginning of the spec or after whitespace;
+a '#' within a field is part of the field.
+
 Predefined schedules
 
 You may use one of several pre-defined schedules in place of a cron expression.
@@ -104,8 +141,18 @@ Time zones
 All interpretation and scheduling is done in the machine's local time zone (as
 provided by the Go time package (http://www.golang.org/pkg/time).
 
-Be aware that jobs scheduled during daylight-savings leap-ahead transitions will
-not be run!
+An individual schedule may be computed in another zone by prefixing its spec
+with CRON_TZ= and the name of the zone, or with AddFuncInLocation:
+
+	CRON_TZ=Asia/Tokyo 0 0 9 * * ?
+
+runs at 9 a.m. Tokyo time, whatever the location of the Cron.
+
+Around daylight saving changes, schedules follow the wall clock. When clocks
+spring forward, activations in the hour skipped run in the hour after it, e.g.
+a job at 2:30 runs at 3:30. When they fall back, a job at fixed hours runs in
+the first occurrence of the hour repeated only, so it still runs once that day,
+while a job whose hour field is * runs in both.
 
 Thread safety
 
@@ -117,13 +164,13 @@ ensures that invocations have a clear happens-before ordering between them.
 
 Implementation
 
-Cron entries are stored in an array, sorted by their next activation time.  Cron
-sleeps until the next job is due to be run.
+Cron entries are stored in a min-heap, ordered by their next activation time.
+Cron sleeps until the next job is due to be run.
 
 Upon waking:
  - it runs each entry that is active on that second
  - it calculates the next run times for the jobs that were run
- - it re-sorts the array of entries by next activation time.
+ - it moves only those entries to their new place in the heap.
  - it goes to sleep until the soonest job.
 */
 package cron
@@ -0,0 +1,35 @@
+package cron
+
+import (
+	"expvar"
+	"time"
+)
+
+// PublishExpvar publishes the Cron's Stats through the expvar package, as a
+// map named prefix with the keys "runs", "active", "entries" and "next". The
+// values are computed from Stats whenever the variables are read, e.g. at
+// /debug/vars.
+//
+// Publishing again with the same prefix, for this or another Cron, replaces
+// the values instead of panicking like expvar.Publish. If prefix is already
+// taken by a variable that is not a map, nothing is published and the
+// conflict is logged.
+func (c *Cron) PublishExpvar(prefix string) {
+	m, ok := expvar.Get(prefix).(*expvar.Map)
+	if !ok {
+		if expvar.Get(prefix) != nil {
+			c.logf("cron: cannot publish expvar %q: name taken by another variable", prefix)
+			return
+		}
+		m = expvar.NewMap(prefix)
+	}
+	m.Set("runs", expvar.Func(func() interface{} { return c.Stats().Runs }))
+	m.Set("active", expvar.Func(func() interface{} { return c.Stats().Active }))
+	m.Set("entries", expvar.Func(func() interface{} { return c.Stats().Entries }))
+	m.Set("next", expvar.Func(func() interface{} {
+		if next := c.Stats().Next; !next.IsZero() {
+			return next.Format(time.RFC3339)
+		}
+		return ""
+	}))
+}
@@ -0,0 +1,36 @@
+package cron
+
+import (
+	"encoding/json"
+	"expvar"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	cron := New()
+	cron.AddFunc("0 0 0 1 1 ?", func() {})
+	cron.PublishExpvar("cron_test")
+
+	other := New()
+	other.AddFunc("0 0 0 1 1 ?", func() {})
+	other.AddFunc("0 0 0 1 1 ?", func() {})
+	other.PublishExpvar("cron_test")
+
+	var vars map[string]interface{}
+	if err := json.Unmarshal([]byte(expvar.Get("cron_test").String()), &vars); err != nil {
+		t.Fatal(err)
+	}
+	if vars["entries"] != float64(2) || vars["runs"] != float64(0) || vars["next"] != "" {
+		t.Errorf("expected the stats of the Cron published last, got %v", vars)
+	}
+
+	var buf syncWriter
+	cron.ErrorLog = log.New(&buf, "", 0)
+	expvar.NewInt("cron_test_taken")
+	cron.PublishExpvar("cron_test_taken")
+	if !strings.Contains(buf.String(), "name taken") {
+		t.Errorf("expected the conflict to be logged, got %q", buf.String())
+	}
+}
@@ -0,0 +1,109 @@
+package cron
+
+import "time"
+
+// Group is a set of entries of a Cron that are paused, resumed and removed as
+// a unit, e.g. the jobs of a module that starts and stops with it. A Group is
+// only a label: entries added through it have Entry.Group set to its name and
+// otherwise behave like any other entry.
+type Group struct {
+	cron *Cron
+	name string
+}
+
+// Group returns the group of entries with the given name. Groups need not be
+// created beforehand, and Group may be called repeatedly for the same name.
+func (c *Cron) Group(name string) *Group {
+	return &Group{cron: c, name: name}
+}
+
+// Name returns the name of the group.
+func (g *Group) Name() string {
+	return g.name
+}
+
+// AddFunc adds a func to the group, to be run on the given schedule.
+func (g *Group) AddFunc(spec string, cmd func()) error {
+	return g.AddNameFunc("", spec, cmd)
+}
+
+// AddNameFunc adds a named func to the group, to be run on the given schedule.
+func (g *Group) AddNameFunc(name, spec string, cmd func()) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	return g.cron.addEntry(&Entry{
+		Schedule: schedule,
+		Job:      FuncJob(cmd),
+		Name:     name,
+		Spec:     spec,
+		Group:    g.name,
+	})
+}
+
+// PauseAll pauses every entry of the group; see Entry.Paused.
+func (g *Group) PauseAll() {
+	g.cron.PauseWhere(g.contains)
+}
+
+// ResumeAll resumes every entry of the group.
+func (g *Group) ResumeAll() {
+	g.cron.ResumeWhere(g.contains)
+}
+
+// contains returns true if e belongs to the group.
+func (g *Group) contains(e *Entry) bool {
+	return e.Group == g.name
+}
+
+// RemoveAll removes every entry of the group and returns how many there were.
+func (g *Group) RemoveAll() int {
+	removed := 0
+	g.cron.do(func() {
+		removed = g.cron.removeWhere(g.contains)
+	})
+	return removed
+}
+
+// SetGroupSpread spreads the activations of the entries of the named group so
+// that they are at least gap apart: whenever the next activation of an entry
+// falls within gap of that of another entry of the group, it is pushed back
+// to gap after it. This spreads the load of heavy jobs that share a schedule
+// without tuning a random delay for each. The random delay, if any, is
+// applied first and the result is spread, so a spread entry may run up to
+// gap times the size of the group later than its delay alone implies. A gap
+// of zero or less removes the spread. It applies from the next activation of
+// each entry on.
+func (c *Cron) SetGroupSpread(group string, gap time.Duration) {
+	c.do(func() {
+		if gap <= 0 {
+			delete(c.spread, group)
+			return
+		}
+		if c.spread == nil {
+			c.spread = make(map[string]time.Duration)
+		}
+		c.spread[group] = gap
+	})
+}
+
+// spreadNext pushes back the next activation of e until it is at least gap
+// away from those of the other scheduled entries of its group.
+func (c *Cron) spreadNext(e *Entry, gap time.Duration) {
+	if e.Next.IsZero() {
+		return
+	}
+	for moved := true; moved; {
+		moved = false
+		for _, other := range c.entries {
+			if other == e || other.Group != e.Group || other.Next.IsZero() {
+				continue
+			}
+			if d := e.Next.Sub(other.Next); d > -gap && d < gap {
+				e.Next = other.Next.Add(gap)
+				moved = true
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package cron
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Test that pausing, resuming and removing a group leaves other groups alone.
+func TestGroup(t *testing.T) {
+	var runsA, runsB int32
+	cron := New()
+	a, b := cron.Group("a"), cron.Group("b")
+	a.AddNameFunc("a1", "* * * * * ?", func() { atomic.AddInt32(&runsA, 1) })
+	a.AddFunc("* * * * * ?", func() { atomic.AddInt32(&runsA, 1) })
+	b.AddNameFunc("b1", "* * * * * ?", func() { atomic.AddInt32(&runsB, 1) })
+	cron.AddNameFunc("plain", "* * * * * ?", func() {})
+
+	now := time.Now()
+	cron.Tick(now)
+	tick := func(i int) {
+		cron.Tick(now.Add(time.Duration(i) * time.Second))
+		cron.jobs.Wait()
+	}
+
+	a.PauseAll()
+	tick(1)
+	if n := atomic.LoadInt32(&runsA); n != 0 {
+		t.Errorf("expected the paused group not to run, ran %d times", n)
+	}
+	if n := atomic.LoadInt32(&runsB); n != 1 {
+		t.Errorf("expected the other group to run once, ran %d times", n)
+	}
+	if e, _ := cron.GetEntry("a1"); !e.Paused || e.Group != "a" || e.SkipReasons["paused"] != 1 {
+		t.Errorf("expected a1 to be paused in group a, got %+v", e)
+	}
+
+	a.ResumeAll()
+	tick(2)
+	if n := atomic.LoadInt32(&runsA); n != 2 {
+		t.Errorf("expected both entries of the resumed group to run, ran %d times", n)
+	}
+
+	if n := a.RemoveAll(); n != 2 {
+		t.Errorf("expected 2 entries removed, got %d", n)
+	}
+	if n := a.RemoveAll(); n != 0 {
+		t.Errorf("expected nothing left to remove, got %d", n)
+	}
+	if n := len(cron.Entries()); n != 2 {
+		t.Errorf("expected 2 entries to remain, got %d", n)
+	}
+	tick(3)
+	if n := atomic.LoadInt32(&runsB); n != 3 {
+		t.Errorf("expected the other group to keep running, ran %d times", n)
+	}
+}
+
+// Test that removing most entries through a group shrinks the entries slice.
+func TestGroupRemoveAllShrinks(t *testing.T) {
+	cron := New()
+	g := cron.Group("bulk")
+	for i := 0; i < 200; i++ {
+		g.AddFunc("0 0 0 1 1 ?", func() {})
+	}
+	cron.AddFunc("0 0 0 1 1 ?", func() {})
+	if n := g.RemoveAll(); n != 200 {
+		t.Errorf("expected 200 entries removed, got %d", n)
+	}
+	if c := cap(cron.entries); c > minShrinkCap {
+		t.Errorf("expected the entries slice to shrink, capacity is %d", c)
+	}
+}
+
+// Test that entries of a spread group sharing a schedule are pushed apart,
+// and stay apart once they have run.
+func TestSetGroupSpread(t *testing.T) {
+	cron := NewWithLocation(time.UTC)
+	heavy := cron.Group("heavy")
+	for _, name := range []string{"a", "b", "c"} {
+		heavy.AddNameFunc(name, "0 * * * * *", func() {})
+	}
+	cron.AddNameFunc("other", "0 * * * * *", func() {})
+	cron.SetGroupSpread("heavy", 10*time.Second)
+
+	start := time.Date(2024, 1, 1, 12, 0, 30, 0, time.UTC)
+	check := func(minute time.Time) {
+		for name, offset := range map[string]time.Duration{"a": 0, "b": 10 * time.Second, "c": 20 * time.Second, "other": 0} {
+			if e, _ := cron.GetEntry(name); !e.Next.Equal(minute.Add(offset)) {
+				t.Errorf("expected %s at %v, got %v", name, minute.Add(offset), e.Next)
+			}
+		}
+	}
+	cron.Tick(start)
+	check(start.Add(30 * time.Second))
+	cron.Tick(start.Add(time.Minute))
+	check(start.Add(90 * time.Second))
+}
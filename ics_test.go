@@ -0,0 +1,96 @@
+package cron
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportICS(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("Asia/Tokyo not available:", err)
+	}
+
+	cron := NewWithLocation(loc)
+	cron.AddNameFunc("report, hourly", "0 0 * * * *", func() {})
+	cron.AddNameFunc("yearly", "0 0 0 1 1 *", func() {})
+
+	data, err := cron.ExportICS(3 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ics := string(data)
+
+	if !strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(ics, "END:VCALENDAR\r\n") {
+		t.Errorf("expected a VCALENDAR document, got:\n%s", ics)
+	}
+	if n := strings.Count(ics, "BEGIN:VEVENT\r\n"); n != 3 {
+		t.Errorf("expected 3 events within the window, got %d", n)
+	}
+	if n := strings.Count(ics, "SUMMARY:report\\, hourly\r\n"); n != 3 {
+		t.Errorf("expected 3 escaped summaries, got %d", n)
+	}
+	if n := strings.Count(ics, "DTSTART:"); n != 3 || strings.Contains(ics, "TZID") {
+		t.Errorf("expected 3 DTSTART values in UTC, got:\n%s", ics)
+	}
+	if n := strings.Count(ics, "-report\\, hourly@cron\r\n"); n != 3 {
+		t.Errorf("expected 3 UIDs derived from the entry name, got %d", n)
+	}
+}
+
+// Test that exporting twice yields the same UIDs, so that calendar
+// applications update events instead of duplicating them.
+func TestExportICSStableUIDs(t *testing.T) {
+	uids := func(c *Cron) []string {
+		data, err := c.ExportICS(3 * time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var uids []string
+		for _, line := range strings.Split(string(data), "\r\n") {
+			if strings.HasPrefix(line, "UID:") {
+				uids = append(uids, line)
+			}
+		}
+		return uids
+	}
+
+	cron := New()
+	cron.AddNameFunc("hourly", "0 0 * * * *", func() {})
+	cron.AddFunc("0 30 * * * *", func() {})
+	first := uids(cron)
+	cron.AddNameFunc("daily", "0 0 0 * * *", func() {})
+	second := uids(cron)
+
+	seen := make(map[string]bool)
+	for _, uid := range second {
+		seen[uid] = true
+	}
+	for _, uid := range first {
+		if !seen[uid] {
+			t.Errorf("expected %q to be exported again", uid)
+		}
+	}
+}
+
+func TestExportICSInvalidWindow(t *testing.T) {
+	if _, err := New().ExportICS(0); err == nil {
+		t.Error("expected an error for a zero window")
+	}
+}
+
+func TestWriteICSLineFolds(t *testing.T) {
+	var buf bytes.Buffer
+	writeICSLine(&buf, "SUMMARY:"+strings.Repeat("é", 100))
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > icsLineLimit {
+			t.Errorf("line exceeds %d octets: %q", icsLineLimit, line)
+		}
+	}
+	unfolded := strings.Replace(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n ", "", -1)
+	if unfolded != "SUMMARY:"+strings.Repeat("é", 100) {
+		t.Errorf("folding altered the content: %q", unfolded)
+	}
+}
@@ -0,0 +1,72 @@
+package cron
+
+import (
+	"fmt"
+	"time"
+)
+
+// locationSchedule activates like its schedule, computed in its own location
+// instead of the Cron's.
+type locationSchedule struct {
+	schedule Schedule
+	location *time.Location
+}
+
+// InLocation returns a schedule that activates like s, with s computed in loc
+// whatever the location of the Cron that runs it. For example, "0 0 0 * * ?"
+// in America/New_York activates at midnight New York time, following its
+// daylight saving changes, even in a Cron that runs in UTC. Activations are
+// returned in loc.
+//
+// If s has a String method, so does the returned schedule, which writes it
+// after a time zone prefix, e.g. "CRON_TZ=America/New_York 0 0 0 * * *".
+//
+// A nil loc returns s itself, which follows the location of the Cron.
+func InLocation(s Schedule, loc *time.Location) Schedule {
+	if loc == nil {
+		return s
+	}
+	if _, ok := s.(fmt.Stringer); ok {
+		return locationStringer{locationSchedule{s, loc}}
+	}
+	return locationSchedule{s, loc}
+}
+
+// locationStringer is a locationSchedule whose schedule is a fmt.Stringer.
+// It is a type of its own so that a schedule that cannot be written as a spec
+// does not gain a String method by being put in a location.
+type locationStringer struct {
+	locationSchedule
+}
+
+// String returns the spec of the schedule preceded by its time zone, in the
+// form accepted by Parse.
+func (s locationStringer) String() string {
+	return timeZonePrefix + s.location.String() + " " + s.schedule.(fmt.Stringer).String()
+}
+
+// scheduleLocation returns the location of a schedule returned by InLocation.
+func scheduleLocation(s Schedule) (*time.Location, bool) {
+	switch s := s.(type) {
+	case locationSchedule:
+		return s.location, true
+	case locationStringer:
+		return s.location, true
+	}
+	return nil, false
+}
+
+// Next returns the next activation of the schedule, in its location.
+func (s locationSchedule) Next(t time.Time) time.Time {
+	return s.schedule.Next(t.In(s.location))
+}
+
+// RandomNext returns the next delayed activation, in the schedule's location.
+func (s locationSchedule) RandomNext(t time.Time, delayRange int) time.Time {
+	return s.schedule.RandomNext(t.In(s.location), delayRange)
+}
+
+// randomNext returns the next delayed activation, drawing the delay from intn.
+func (s locationSchedule) randomNext(t time.Time, delayRange int, intn func(n int64) int64) time.Time {
+	return randomNextFrom(s.schedule, t.In(s.location), delayRange, intn)
+}
@@ -0,0 +1,169 @@
+package cron
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Test that an entry with a location of its own fires at its wall-clock time
+// there across a daylight saving change, in a Cron running in UTC.
+func TestAddFuncInLocation(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("America/New_York not available:", err)
+	}
+
+	// New York moves from EST to EDT on 2024-03-10.
+	clk := &fakeClock{now: time.Date(2024, 3, 9, 12, 0, 0, 0, ny)}
+	cron := NewWithLocation(time.UTC)
+	cron.SetClock(clk)
+	ran := 0
+	if err := cron.AddFuncInLocation("midnight", "0 0 0 * * ?", ny, func() { ran++ }); err != nil {
+		t.Fatal(err)
+	}
+	cron.AddNameFunc("utc", "0 0 0 * * ?", func() {})
+	cron.SetRunInline("midnight", true)
+
+	expected := []time.Time{
+		time.Date(2024, 3, 10, 5, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 11, 4, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 12, 4, 0, 0, 0, time.UTC),
+	}
+	times := cron.NextN("midnight", 3)
+	for i, next := range times {
+		if !next.Equal(expected[i]) || next.In(ny).Hour() != 0 {
+			t.Errorf("expected activation %d at %v, got %v", i, expected[i], next)
+		}
+	}
+	if len(times) != len(expected) {
+		t.Errorf("expected %d activations, got %v", len(expected), times)
+	}
+	if next := cron.NextN("utc", 1); !next[0].Equal(time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the other entry to keep the location of the cron, got %v", next)
+	}
+	if e, _ := cron.GetEntry("midnight"); e.Location != ny {
+		t.Errorf("expected the entry location %v, got %v", ny, e.Location)
+	}
+
+	cron.Tick(clk.Now())
+	for _, now := range expected {
+		cron.Tick(now.Add(-time.Second))
+		cron.Tick(now)
+	}
+	if ran != len(expected) {
+		t.Errorf("expected %d runs at midnight in New York, got %d", len(expected), ran)
+	}
+}
+
+// Test that an entry added with a CRON_TZ= spec records its location, and that
+// the spec round-trips through the config.
+func TestTimeZoneSpecEntry(t *testing.T) {
+	cron := NewWithLocation(time.UTC)
+	if err := cron.AddNameFunc("tokyo", "CRON_TZ=Asia/Tokyo 0 0 9 * * ?", func() {}); err != nil {
+		t.Skip("Asia/Tokyo not available:", err)
+	}
+	if e, _ := cron.GetEntry("tokyo"); e.Location == nil || e.Location.String() != "Asia/Tokyo" {
+		t.Errorf("expected the entry location Asia/Tokyo, got %+v", e)
+	}
+
+	data, err := cron.MarshalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadConfig(data, func(string) Job { return FuncJob(func() {}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := loaded.MarshalConfig()
+	if string(again) != string(data) {
+		t.Errorf("expected the config to round-trip:\n%s\n%s", data, again)
+	}
+}
+
+// Test that descriptor schedules follow the location of their entry like
+// field-based ones.
+func TestDescriptorInLocation(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("Asia/Tokyo not available:", err)
+	}
+	clk := &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	cron := NewWithLocation(time.UTC)
+	cron.SetClock(clk)
+	cron.AddFuncInLocation("daily", "@daily", tokyo, func() {})
+	cron.AddNameFunc("midnight", "CRON_TZ=Asia/Tokyo @midnight", func() {})
+
+	expected := time.Date(2024, 6, 2, 0, 0, 0, 0, tokyo)
+	for _, name := range []string{"daily", "midnight"} {
+		if next := cron.NextN(name, 1); len(next) != 1 || !next[0].Equal(expected) {
+			t.Errorf("%s: expected %v, got %v", name, expected, next)
+		}
+	}
+}
+
+// Test that across daylight saving changes a job at a fixed time runs once
+// per day: at the time after the gap when clocks spring forward, and at the
+// first occurrence only when they fall back, while an hourly job runs in both
+// occurrences of the repeated hour.
+func TestDaylightSavingRunsOnce(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("America/New_York not available:", err)
+	}
+
+	tests := []struct {
+		name    string
+		from    time.Time
+		spec    string
+		runs    int32
+		lastRun time.Time
+	}{
+		{"spring forward", time.Date(2012, 3, 11, 0, 0, 0, 0, ny), "0 30 2 * * ?", 1,
+			time.Date(2012, 3, 11, 3, 30, 0, 0, ny)},
+		{"fall back", time.Date(2012, 11, 4, 0, 0, 0, 0, ny), "0 30 1 * * ?", 1,
+			time.Date(2012, 11, 4, 1, 30, 0, 0, ny)},
+		{"fall back hourly", time.Date(2012, 11, 4, 0, 0, 0, 0, ny), "0 30 * * * ?", 6,
+			time.Date(2012, 11, 4, 4, 30, 0, 0, ny)},
+	}
+	for _, test := range tests {
+		job := &countJob{}
+		cron := NewWithLocation(ny)
+		cron.AddNameJob("job", test.spec, job)
+		for now := test.from; now.Before(test.from.Add(6 * time.Hour)); now = now.Add(15 * time.Minute) {
+			cron.Tick(now)
+		}
+		cron.jobs.Wait()
+		if runs := atomic.LoadInt32(&job.runs); runs != test.runs {
+			t.Errorf("%s: expected %d runs, got %d", test.name, test.runs, runs)
+		}
+		if e, _ := cron.GetEntry("job"); !e.Prev.Equal(test.lastRun) {
+			t.Errorf("%s: expected the last run at %v, got %v", test.name, test.lastRun, e.Prev)
+		}
+	}
+}
+
+// Test that a nil location is rejected by AddFuncInLocation, and leaves a
+// schedule in the location of the Cron with InLocation, rather than panicking
+// on the scheduler goroutine.
+func TestInNilLocation(t *testing.T) {
+	cron := New()
+	if err := cron.AddFuncInLocation("nil", "0 0 0 * * ?", nil, func() {}); err == nil {
+		t.Error("expected an error for a nil location")
+	}
+	if n := cron.EntryCount(); n != 0 {
+		t.Errorf("expected no entry to be added, got %d", n)
+	}
+
+	daily, _ := Parse("0 0 0 * * ?")
+	if s := InLocation(daily, nil); s != daily {
+		t.Errorf("expected the schedule itself for a nil location, got %v", s)
+	}
+	cron.NameAndDelaySchedule("daily", InLocation(daily, nil), 0, FuncJob(func() {}))
+	now := time.Now()
+	cron.Tick(now)
+	cron.Tick(now.Add(24 * time.Hour))
+	if e, ok := cron.GetEntry("daily"); !ok || e.Location != nil || e.Prev.IsZero() {
+		t.Errorf("expected the entry to run in the location of the Cron, got %v", e)
+	}
+}
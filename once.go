@@ -0,0 +1,33 @@
+package cron
+
+import "time"
+
+// onceSchedule activates a single time, at a given instant.
+type onceSchedule struct {
+	at time.Time
+}
+
+// At returns a schedule that activates once, at t, and never after.
+func At(t time.Time) Schedule {
+	return onceSchedule{t}
+}
+
+// String describes the schedule, e.g. "at 2012-12-01T09:00:00Z".
+func (s onceSchedule) String() string {
+	return "at " + s.at.Format(time.RFC3339)
+}
+
+// Next returns the instant of the schedule if it is after t, and the zero
+// time otherwise, so that once it has run the entry sorts last.
+func (s onceSchedule) Next(t time.Time) time.Time {
+	if s.at.After(t) {
+		return s.at
+	}
+	return time.Time{}
+}
+
+// RandomNext returns the instant of the schedule delayed by up to delayRange
+// seconds, or the zero time once it has passed.
+func (s onceSchedule) RandomNext(t time.Time, delayRange int) time.Time {
+	return jitter(s, t, delayRange, cryptoIntn)
+}
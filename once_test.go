@@ -0,0 +1,100 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAt(t *testing.T) {
+	at := getTime("Mon Jul 9 14:45 2012")
+	s := At(at)
+	if next := s.Next(at.Add(-time.Hour)); !next.Equal(at) {
+		t.Errorf("expected %v before the instant, got %v", at, next)
+	}
+	if next := s.Next(at); !next.IsZero() {
+		t.Errorf("expected the zero time at the instant, got %v", next)
+	}
+	if next := s.Next(at.Add(time.Hour)); !next.IsZero() {
+		t.Errorf("expected the zero time after the instant, got %v", next)
+	}
+	for i := 0; i < 100; i++ {
+		next := s.RandomNext(at.Add(-time.Hour), 60)
+		if next.Before(at) || !next.Before(at.Add(time.Minute)) {
+			t.Fatalf("expected a delayed time within a minute of %v, got %v", at, next)
+		}
+	}
+}
+
+// Test that a func added with AddOnceFunc runs exactly once, at its time, and
+// is then left with a zero Next.
+func TestAddOnceFunc(t *testing.T) {
+	now := time.Now()
+	at := now.Add(time.Hour)
+	cron := NewWithLocation(time.UTC)
+	runs := 0
+	if err := cron.AddOnceFunc("once", at, func() { runs++ }); err != nil {
+		t.Fatal(err)
+	}
+	cron.SetRunInline("once", true)
+	if err := cron.AddOnceFunc("past", now.Add(-time.Hour), func() {}); err == nil {
+		t.Error("expected an error for a time in the past")
+	}
+
+	cron.Tick(now)
+	if e, _ := cron.GetEntry("once"); !e.Next.Equal(at) {
+		t.Errorf("expected the entry to be next at %v, got %v", at, e.Next)
+	}
+	cron.Tick(at)
+	cron.Tick(at.Add(time.Minute))
+	cron.Tick(at.Add(24 * time.Hour))
+	if runs != 1 {
+		t.Errorf("expected 1 run, got %d", runs)
+	}
+	e, ok := cron.GetEntry("once")
+	if !ok || !e.Next.IsZero() || !e.Prev.Equal(at) {
+		t.Errorf("expected the entry to be kept with a zero Next after its run, got %v", e)
+	}
+}
+
+// Test that an entry set to be removed when done leaves the Cron once its
+// schedule expires, with OnEntryDone notified, while other entries stay.
+func TestRemoveWhenDone(t *testing.T) {
+	now := time.Now()
+	at := now.Add(time.Hour)
+	done := make(chan string, 1)
+	cron := NewWithLocation(time.UTC)
+	cron.OnEntryDone = func(name string) { done <- name }
+	cron.AddOnceFunc("once", at, func() {})
+	cron.AddOnceFunc("kept", at, func() {})
+	cron.AddNameFunc("hourly", "0 0 * * * ?", func() {})
+	if err := cron.SetRemoveWhenDone("once", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := cron.SetRemoveWhenDone("missing", true); err == nil {
+		t.Error("expected an error for an unknown name")
+	}
+
+	cron.Tick(now)
+	if n := cron.EntryCount(); n != 3 {
+		t.Errorf("expected 3 entries before the run, got %d", n)
+	}
+	cron.Tick(at)
+	cron.jobs.Wait()
+	if n := cron.EntryCount(); n != 2 {
+		t.Errorf("expected 2 entries after the run, got %d", n)
+	}
+	if _, ok := cron.GetEntry("once"); ok {
+		t.Error("expected the expired entry to be removed")
+	}
+	if e, ok := cron.GetEntry("kept"); !ok || !e.Next.IsZero() {
+		t.Errorf("expected the other one-shot entry to be kept, got %v", e)
+	}
+	select {
+	case name := <-done:
+		if name != "once" {
+			t.Errorf("expected OnEntryDone for once, got %q", name)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected OnEntryDone to be called")
+	}
+}
@@ -1,6 +1,7 @@
 package cron
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
@@ -15,14 +16,17 @@ import (
 type ParseOption int
 
 const (
-	Second      ParseOption = 1 << iota // Seconds field, default 0
-	Minute                              // Minutes field, default 0
-	Hour                                // Hours field, default 0
-	Dom                                 // Day of month field, default *
-	Month                               // Month field, default *
-	Dow                                 // Day of week field, default *
-	DowOptional                         // Optional day of week field, default *
-	Descriptor                          // Allow descriptors such as @monthly, @weekly, etc.
+	Second         ParseOption = 1 << iota // Seconds field, default 0
+	Minute                                 // Minutes field, default 0
+	Hour                                   // Hours field, default 0
+	Dom                                    // Day of month field, default *
+	Month                                  // Month field, default *
+	Dow                                    // Day of week field, default *
+	DowOptional                            // Optional day of week field, default *
+	Descriptor                             // Allow descriptors such as @monthly, @weekly, etc.
+	DayAnd                                 // Require both day of month and day of week to match
+	Comments                               // Ignore a trailing "# comment", as in crontab files
+	SecondOptional                         // Optional seconds field, default 0
 )
 
 var places = []ParseOption{
@@ -43,6 +47,10 @@ var defaults = []string{
 	"*",
 }
 
+// ErrEmptySpec is returned when parsing a spec that is empty or contains only
+// whitespace, so callers can tell a missing schedule apart from a malformed one.
+var ErrEmptySpec = errors.New("Empty spec string")
+
 // A custom Parser that can be configured.
 type Parser struct {
 	options   ParseOption
@@ -51,20 +59,23 @@ type Parser struct {
 
 // Creates a custom Parser with custom options.
 //
-//  // Standard parser without descriptors
-//  specParser := NewParser(Minute | Hour | Dom | Month | Dow)
-//  sched, err := specParser.Parse("0 0 15 */3 *")
-//
-//  // Same as above, just excludes time fields
-//  subsParser := NewParser(Dom | Month | Dow)
-//  sched, err := specParser.Parse("15 */3 *")
+//	// Standard parser without descriptors
+//	specParser := NewParser(Minute | Hour | Dom | Month | Dow)
+//	sched, err := specParser.Parse("0 0 15 */3 *")
 //
-//  // Same as above, just makes Dow optional
-//  subsParser := NewParser(Dom | Month | DowOptional)
-//  sched, err := specParser.Parse("15 */3")
+//	// Same as above, just excludes time fields
+//	subsParser := NewParser(Dom | Month | Dow)
+//	sched, err := specParser.Parse("15 */3 *")
 //
+//	// Same as above, just makes Dow optional
+//	subsParser := NewParser(Dom | Month | DowOptional)
+//	sched, err := specParser.Parse("15 */3")
 func NewParser(options ParseOption) Parser {
 	optionals := 0
+	if options&SecondOptional > 0 {
+		options |= Second
+		optionals++
+	}
 	if options&DowOptional > 0 {
 		options |= Dow
 		optionals++
@@ -72,12 +83,51 @@ func NewParser(options ParseOption) Parser {
 	return Parser{options, optionals}
 }
 
+// ParserFeatures describes which fields and features a Parser accepts.
+type ParserFeatures struct {
+	Second         bool // Seconds field
+	SecondOptional bool // Seconds field may be omitted
+	Minute         bool // Minutes field
+	Hour           bool // Hours field
+	Dom            bool // Day of month field
+	Month          bool // Month field
+	Dow            bool // Day of week field
+	DowOptional    bool // Day of week field may be omitted
+	Descriptor     bool // Descriptors such as @monthly, @every, etc.
+	DayAnd         bool // Day of month and day of week must both match
+	Comments       bool // A trailing "# comment" is ignored
+}
+
+// Features reports the fields and features enabled for the parser, e.g. so
+// that a user interface can adapt its help text to the configured dialect.
+func (p Parser) Features() ParserFeatures {
+	return ParserFeatures{
+		Second:         p.options&Second > 0,
+		SecondOptional: p.options&SecondOptional > 0,
+		Minute:         p.options&Minute > 0,
+		Hour:           p.options&Hour > 0,
+		Dom:            p.options&Dom > 0,
+		Month:          p.options&Month > 0,
+		Dow:            p.options&Dow > 0,
+		DowOptional:    p.options&DowOptional > 0,
+		Descriptor:     p.options&Descriptor > 0,
+		DayAnd:         p.options&DayAnd > 0,
+		Comments:       p.options&Comments > 0,
+	}
+}
+
 // Parse returns a new crontab schedule representing the given spec.
 // It returns a descriptive error if the spec is not valid.
 // It accepts crontab specs and features configured by NewParser.
 func (p Parser) Parse(spec string) (Schedule, error) {
-	if len(spec) == 0 {
-		return nil, fmt.Errorf("Empty spec string")
+	if p.options&Comments > 0 {
+		spec = stripComment(spec)
+	}
+	if len(strings.TrimSpace(spec)) == 0 {
+		return nil, ErrEmptySpec
+	}
+	if strings.HasPrefix(spec, timeZonePrefix) {
+		return p.parseInLocation(spec)
 	}
 	if spec[0] == '@' && p.options&Descriptor > 0 {
 		return parseDescriptor(spec)
@@ -103,6 +153,12 @@ func (p Parser) Parse(spec string) (Schedule, error) {
 		return nil, fmt.Errorf("Expected %d to %d fields, found %d: %s", min, max, count, spec)
 	}
 
+	// An optional seconds field is the leftmost one, so when fields are
+	// missing it is the first to be left out.
+	if p.options&SecondOptional > 0 && len(fields) < max {
+		fields = append([]string{defaults[0]}, fields...)
+	}
+
 	// Fill in missing fields
 	fields = expandFields(fields, p.options)
 
@@ -117,27 +173,62 @@ func (p Parser) Parse(spec string) (Schedule, error) {
 	}
 
 	var (
-		second     = field(fields[0], seconds)
-		minute     = field(fields[1], minutes)
-		hour       = field(fields[2], hours)
-		dayofmonth = field(fields[3], dom)
-		month      = field(fields[4], months)
-		dayofweek  = field(fields[5], dow)
+		second = field(fields[0], seconds)
+		minute = field(fields[1], minutes)
+		hour   = field(fields[2], hours)
+		month  = field(fields[4], months)
+	)
+	var (
+		lastdom bool
+		nearest uint64
+		lastdow uint64
+		nthdow  uint64
 	)
+	fields[3], lastdom = splitLastDom(fields[3])
+	if err == nil {
+		fields[3], nearest, err = splitNearestWeekday(fields[3])
+	}
+	if err == nil {
+		fields[5], lastdow, err = splitLastDow(fields[5])
+	}
+	if err == nil {
+		fields[5], nthdow, err = splitNthDow(fields[5])
+	}
+	dayofmonth := field(fields[3], dom)
+	dayofweek := field(fields[5], dow)
 	if err != nil {
 		return nil, err
 	}
 
 	return &SpecSchedule{
-		Second: second,
-		Minute: minute,
-		Hour:   hour,
-		Dom:    dayofmonth,
-		Month:  month,
-		Dow:    dayofweek,
+		Second:  second,
+		Minute:  minute,
+		Hour:    hour,
+		Dom:     dayofmonth,
+		Month:   month,
+		Dow:     dayofweek,
+		LastDom: lastdom,
+		LastDow: lastdow,
+		NthDow:  nthdow,
+		DayAnd:  p.options&DayAnd > 0,
+
+		NearestWeekday: nearest,
 	}, nil
 }
 
+// stripComment removes a trailing comment and surrounding whitespace from
+// spec. A comment starts with a '#' at the beginning of the spec or after
+// whitespace; a '#' within a field is left alone.
+func stripComment(spec string) string {
+	for i, r := range spec {
+		if r == '#' && (i == 0 || spec[i-1] == ' ' || spec[i-1] == '\t') {
+			spec = spec[:i]
+			break
+		}
+	}
+	return strings.TrimSpace(spec)
+}
+
 func expandFields(fields []string, options ParseOption) []string {
 	n := 0
 	count := len(fields)
@@ -181,10 +272,23 @@ var defaultParser = NewParser(
 // It accepts
 //   - Full crontab specs, e.g. "* * * * * ?"
 //   - Descriptors, e.g. "@midnight", "@every 1h30m"
+//
+// Either may be preceded by a time zone, e.g. "CRON_TZ=Asia/Tokyo @daily", to
+// compute the schedule in that zone instead of the location of the Cron.
+//
+// The seconds field always comes first. Use a parser created with the
+// SecondOptional option to accept standard 5-field crontab specs as well.
 func Parse(spec string) (Schedule, error) {
 	return defaultParser.Parse(spec)
 }
 
+// ValidateSpec returns the error Parse would return for spec, or nil if spec
+// is valid, e.g. to check the specs of a configuration before adding them.
+func ValidateSpec(spec string) error {
+	_, err := Parse(spec)
+	return err
+}
+
 // getField returns an Int with the bits set representing all of the times that
 // the field represents or error parsing field value.  A "field" is a comma-separated
 // list of "ranges".
@@ -202,7 +306,9 @@ func getField(field string, r bounds) (uint64, error) {
 }
 
 // getRange returns the bits indicated by the given expression:
-//   number | number "-" number [ "/" number ]
+//
+//	number | number "-" number [ "/" number ]
+//
 // or error parsing range.
 func getRange(expr string, r bounds) (uint64, error) {
 	var (
@@ -265,10 +371,115 @@ func getRange(expr string, r bounds) (uint64, error) {
 	if step == 0 {
 		return 0, fmt.Errorf("Step of range should be a positive number: %s", expr)
 	}
+	if span := r.max - r.min + 1; step > span {
+		return 0, fmt.Errorf("Step of range (%d) beyond span of field (%d): %s", step, span, expr)
+	}
 
 	return getBits(start, end, step) | extra, nil
 }
 
+// splitLastDom separates the "L" expressions of a day-of-month field, for the
+// last day of the month, from its ranges. It returns the remaining ranges and
+// whether there was one.
+func splitLastDom(field string) (string, bool) {
+	var (
+		ranges []string
+		last   bool
+	)
+	for _, expr := range strings.FieldsFunc(field, func(r rune) bool { return r == ',' }) {
+		if strings.ToUpper(expr) == "L" {
+			last = true
+			continue
+		}
+		ranges = append(ranges, expr)
+	}
+	return strings.Join(ranges, ","), last
+}
+
+// splitNearestWeekday separates the "<day>W" expressions of a day-of-month
+// field, e.g. "15W" for the weekday nearest to the 15th, from its ranges. It
+// returns the remaining ranges and the bits of the days named by the former.
+func splitNearestWeekday(field string) (string, uint64, error) {
+	var (
+		ranges  []string
+		nearest uint64
+	)
+	for _, expr := range strings.FieldsFunc(field, func(r rune) bool { return r == ',' }) {
+		if len(expr) < 2 || !strings.HasSuffix(strings.ToUpper(expr), "W") {
+			ranges = append(ranges, expr)
+			continue
+		}
+		day, err := mustParseInt(expr[:len(expr)-1])
+		if err != nil {
+			return "", 0, err
+		}
+		if day < dom.min || day > dom.max {
+			return "", 0, fmt.Errorf("Day of month (%d) out of range (%d-%d): %s", day, dom.min, dom.max, expr)
+		}
+		nearest |= 1 << day
+	}
+	return strings.Join(ranges, ","), nearest, nil
+}
+
+// splitLastDow separates the "<day>L" expressions of a day-of-week field,
+// e.g. "5L" or "FRIL" for the last Friday of the month, from its ranges. It
+// returns the remaining ranges and the bits of the days named by the former.
+func splitLastDow(field string) (string, uint64, error) {
+	var (
+		ranges []string
+		last   uint64
+	)
+	for _, expr := range strings.FieldsFunc(field, func(r rune) bool { return r == ',' }) {
+		if len(expr) < 2 || !strings.HasSuffix(strings.ToUpper(expr), "L") {
+			ranges = append(ranges, expr)
+			continue
+		}
+		day, err := parseIntOrName(expr[:len(expr)-1], dow.names)
+		if err != nil {
+			return "", 0, err
+		}
+		if day < dow.min || day > dow.max {
+			return "", 0, fmt.Errorf("Day of week (%d) out of range (%d-%d): %s", day, dow.min, dow.max, expr)
+		}
+		last |= 1 << day
+	}
+	return strings.Join(ranges, ","), last, nil
+}
+
+// splitNthDow separates the "<day>#<n>" expressions of a day-of-week field,
+// e.g. "2#2" or "TUE#2" for the second Tuesday of the month, from its ranges.
+// It returns the remaining ranges and the bits of the occurrences, as in
+// SpecSchedule.NthDow.
+func splitNthDow(field string) (string, uint64, error) {
+	var (
+		ranges []string
+		nth    uint64
+	)
+	for _, expr := range strings.FieldsFunc(field, func(r rune) bool { return r == ',' }) {
+		i := strings.IndexByte(expr, '#')
+		if i == -1 {
+			ranges = append(ranges, expr)
+			continue
+		}
+		day, err := parseIntOrName(expr[:i], dow.names)
+		if err != nil {
+			return "", 0, err
+		}
+		if day < dow.min || day > dow.max {
+			return "", 0, fmt.Errorf("Day of week (%d) out of range (%d-%d): %s", day, dow.min, dow.max, expr)
+		}
+		n, err := mustParseInt(expr[i+1:])
+		if err != nil {
+			return "", 0, err
+		}
+		if n < 1 || n > 5 {
+			return "", 0, fmt.Errorf("Occurrence (%d) out of range (1-5): %s", n, expr)
+		}
+		nth |= 1 << (8*day + n)
+	}
+	return strings.Join(ranges, ","), nth, nil
+}
+
 // parseIntOrName returns the (possibly-named) integer contained in expr.
 func parseIntOrName(expr string, names map[string]uint) (uint, error) {
 	if names != nil {
@@ -313,6 +524,29 @@ func all(r bounds) uint64 {
 	return getBits(r.min, r.max, 1) | starBit
 }
 
+// timeZonePrefix introduces the time zone of a spec, e.g.
+// "CRON_TZ=Asia/Tokyo 0 0 9 * * ?".
+const timeZonePrefix = "CRON_TZ="
+
+// parseInLocation parses a spec that starts with a time zone prefix, returning
+// the schedule of the rest of the spec in that zone; see InLocation.
+func (p Parser) parseInLocation(spec string) (Schedule, error) {
+	i := strings.IndexAny(spec, " \t")
+	if i == -1 {
+		return nil, fmt.Errorf("Missing spec after time zone %s", spec)
+	}
+	name := spec[len(timeZonePrefix):i]
+	loc, err := time.LoadLocation(name)
+	if name == "" || err != nil {
+		return nil, fmt.Errorf("Unknown time zone %s: %s", name, err)
+	}
+	schedule, err := p.Parse(strings.TrimSpace(spec[i:]))
+	if err != nil {
+		return nil, err
+	}
+	return InLocation(schedule, loc), nil
+}
+
 // parseDescriptor returns a predefined schedule for the expression, or error if none matches.
 func parseDescriptor(descriptor string) (Schedule, error) {
 	switch descriptor {
@@ -373,6 +607,9 @@ func parseDescriptor(descriptor string) (Schedule, error) {
 		if err != nil {
 			return nil, fmt.Errorf("Failed to parse duration %s: %s", descriptor, err)
 		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("Duration of %s must be positive", descriptor)
+		}
 		return Every(duration), nil
 	}
 
@@ -39,6 +39,12 @@ func TestRange(t *testing.T) {
 		{"6", 3, 5, zero, "above maximum"},
 		{"5-3", 3, 5, zero, "beyond end of range"},
 		{"*/0", 0, 0, zero, "should be a positive number"},
+		{"0-30/0", 0, 59, zero, "should be a positive number"},
+		{"*/61", 0, 59, zero, "beyond span of field"},
+
+		{"0-30/5", 0, 59, 1<<0 | 1<<5 | 1<<10 | 1<<15 | 1<<20 | 1<<25 | 1<<30, ""},
+		{"9-17/2", 0, 23, 1<<9 | 1<<11 | 1<<13 | 1<<15 | 1<<17, ""},
+		{"*/60", 0, 59, 1<<0 | starBit, ""},
 	}
 
 	for _, c := range ranges {
@@ -137,14 +143,57 @@ func TestParse(t *testing.T) {
 			expr: "* 5 j * * *",
 			err:  "Failed to parse int from",
 		},
+		{
+			// The seconds field comes first, and the day of week is optional.
+			expr: "0 5 * * *",
+			expected: &SpecSchedule{
+				Second: 1 << seconds.min,
+				Minute: 1 << 5,
+				Hour:   all(hours),
+				Dom:    all(dom),
+				Month:  all(months),
+				Dow:    all(dow),
+			},
+		},
+		{
+			expr: "*/15 30 * * * *",
+			expected: &SpecSchedule{
+				Second: getBits(0, 59, 15) | starBit,
+				Minute: 1 << 30,
+				Hour:   all(hours),
+				Dom:    all(dom),
+				Month:  all(months),
+				Dow:    all(dow),
+			},
+		},
 		{
 			expr:     "@every 5m",
 			expected: ConstantDelaySchedule{Delay: time.Duration(5) * time.Minute},
 		},
+		{
+			expr:     "@every 10s",
+			expected: ConstantDelaySchedule{Delay: 10 * time.Second},
+		},
+		{
+			expr:     "@every 1h",
+			expected: ConstantDelaySchedule{Delay: time.Hour},
+		},
 		{
 			expr: "@every Xm",
 			err:  "Failed to parse duration",
 		},
+		{
+			expr: "@every abc",
+			err:  "Failed to parse duration",
+		},
+		{
+			expr: "@every 0s",
+			err:  "must be positive",
+		},
+		{
+			expr: "@every -5m",
+			err:  "must be positive",
+		},
 		{
 			expr: "@yearly",
 			expected: &SpecSchedule{
@@ -167,6 +216,33 @@ func TestParse(t *testing.T) {
 				Dow:    all(dow),
 			},
 		},
+		{
+			expr: "0 0 0 L * ?",
+			expected: &SpecSchedule{
+				Second:  1 << seconds.min,
+				Minute:  1 << minutes.min,
+				Hour:    1 << hours.min,
+				Month:   all(months),
+				Dow:     all(dow),
+				LastDom: true,
+			},
+		},
+		{
+			expr: "0 0 18 ? * 1,5L",
+			expected: &SpecSchedule{
+				Second:  1 << seconds.min,
+				Minute:  1 << minutes.min,
+				Hour:    1 << 18,
+				Dom:     all(dom),
+				Month:   all(months),
+				Dow:     1 << 1,
+				LastDow: 1 << 5,
+			},
+		},
+		{
+			expr: "0 0 18 ? * 7L",
+			err:  "Day of week (7) out of range",
+		},
 		{
 			expr: "@unrecognized",
 			err:  "Unrecognized descriptor",
@@ -202,8 +278,15 @@ func TestStandardSpecSchedule(t *testing.T) {
 		err      string
 	}{
 		{
-			expr:     "5 * * * *",
-			expected: &SpecSchedule{1 << seconds.min, 1 << 5, all(hours), all(dom), all(months), all(dow)},
+			expr: "5 * * * *",
+			expected: &SpecSchedule{
+				Second: 1 << seconds.min,
+				Minute: 1 << 5,
+				Hour:   all(hours),
+				Dom:    all(dom),
+				Month:  all(months),
+				Dow:    all(dow),
+			},
 		},
 		{
 			expr:     "@every 5m",
@@ -232,3 +315,188 @@ func TestStandardSpecSchedule(t *testing.T) {
 		}
 	}
 }
+
+// Test that each descriptor activates like the spec it stands for, and that
+// unknown ones are rejected.
+func TestDescriptorNext(t *testing.T) {
+	from := time.Date(2012, 7, 9, 15, 4, 5, 0, time.UTC) // a Monday
+	entries := []struct {
+		descriptor string
+		expected   time.Time
+	}{
+		{"@yearly", time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"@annually", time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2012, 8, 1, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2012, 7, 15, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2012, 7, 10, 0, 0, 0, 0, time.UTC)},
+		{"@midnight", time.Date(2012, 7, 10, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2012, 7, 9, 16, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range entries {
+		sched, err := Parse(c.descriptor)
+		if err != nil {
+			t.Errorf("%s => unexpected error %v", c.descriptor, err)
+			continue
+		}
+		if next := sched.Next(from); !next.Equal(c.expected) {
+			t.Errorf("%s => expected %v, got %v", c.descriptor, c.expected, next)
+		}
+	}
+
+	if _, err := Parse("@fortnightly"); err == nil || !strings.Contains(err.Error(), "Unrecognized descriptor: @fortnightly") {
+		t.Errorf("expected an unrecognized descriptor error, got %v", err)
+	}
+}
+
+// Test that a CRON_TZ= prefix computes the schedule in that zone, whatever
+// the location of the time it is given.
+func TestParseTimeZone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("Asia/Tokyo not available:", err)
+	}
+	from := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC) // 21:00 in Tokyo
+	entries := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"CRON_TZ=Asia/Tokyo 0 0 9 * * ?", time.Date(2024, 6, 2, 9, 0, 0, 0, tokyo)},
+		{"CRON_TZ=Asia/Tokyo @daily", time.Date(2024, 6, 2, 0, 0, 0, 0, tokyo)},
+		{"CRON_TZ=Asia/Tokyo @midnight", time.Date(2024, 6, 2, 0, 0, 0, 0, tokyo)},
+		{"CRON_TZ=Asia/Tokyo @every 1h", from.Add(time.Hour)},
+		{"0 0 9 * * ?", time.Date(2024, 6, 2, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range entries {
+		sched, err := Parse(c.spec)
+		if err != nil {
+			t.Errorf("%s => unexpected error %v", c.spec, err)
+			continue
+		}
+		if next := sched.Next(from); !next.Equal(c.expected) {
+			t.Errorf("%s => expected %v, got %v", c.spec, c.expected, next)
+		}
+	}
+
+	for _, spec := range []string{"CRON_TZ=Mars/Olympus 0 0 9 * * ?", "CRON_TZ= 0 0 9 * * ?"} {
+		if _, err := Parse(spec); err == nil || !strings.Contains(err.Error(), "Unknown time zone") {
+			t.Errorf("%s => expected an unknown time zone error, got %v", spec, err)
+		}
+	}
+	if _, err := Parse("CRON_TZ=Asia/Tokyo"); err == nil {
+		t.Error("expected an error for a time zone without a spec")
+	}
+}
+
+// Test that month and weekday names, in any case, parse to the same
+// schedules as their numbers, including in ranges and lists.
+func TestParseNames(t *testing.T) {
+	entries := []struct {
+		named, numbered string
+	}{
+		{"0 0 0 * JAN-MAR MON-FRI", "0 0 0 * 1-3 1-5"},
+		{"0 0 0 * * SAT,SUN", "0 0 0 * * 6,0"},
+		{"0 0 0 * * mon-wed", "0 0 0 * * 1-3"},
+		{"0 0 0 * Dec,1 1,Wed", "0 0 0 * 12,1 1,3"},
+		{"0 0 0 * jun-aug/2 *", "0 0 0 * 6-8/2 *"},
+	}
+	for _, c := range entries {
+		named, err := Parse(c.named)
+		if err != nil {
+			t.Errorf("%s => unexpected error %v", c.named, err)
+			continue
+		}
+		numbered, _ := Parse(c.numbered)
+		if !reflect.DeepEqual(named, numbered) {
+			t.Errorf("%s => expected %b, got %b", c.named, numbered, named)
+		}
+	}
+
+	for _, spec := range []string{"0 0 0 * FOO *", "0 0 0 * * FOO", "0 0 0 * * MON-FOO", "0 0 0 * JANUARY *"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("expected an error parsing %s", spec)
+		}
+	}
+}
+
+func TestEmptySpec(t *testing.T) {
+	for _, spec := range []string{"", "   ", "\t\n"} {
+		if _, err := Parse(spec); err != ErrEmptySpec {
+			t.Errorf("%q => expected ErrEmptySpec, got %v", spec, err)
+		}
+	}
+
+	cron := New()
+	if err := cron.AddFunc(" ", func() {}); err != ErrEmptySpec {
+		t.Errorf("expected AddFunc to return ErrEmptySpec, got %v", err)
+	}
+}
+
+func TestParserFeatures(t *testing.T) {
+	entries := []struct {
+		parser   Parser
+		expected ParserFeatures
+	}{
+		{defaultParser, ParserFeatures{Second: true, Minute: true, Hour: true, Dom: true, Month: true, Dow: true, DowOptional: true, Descriptor: true}},
+		{standardParser, ParserFeatures{Minute: true, Hour: true, Dom: true, Month: true, Dow: true, Descriptor: true}},
+		{NewParser(Dom | Month | DowOptional), ParserFeatures{Dom: true, Month: true, Dow: true, DowOptional: true}},
+	}
+
+	for _, c := range entries {
+		if actual := c.parser.Features(); actual != c.expected {
+			t.Errorf("%+v: expected %+v, got %+v", c.parser, c.expected, actual)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	p := NewParser(Minute | Hour | Dom | Month | Dow | Comments)
+	expected, err := p.Parse("0 9 * * 1-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := p.Parse("  0 9 * * 1-5 # weekday mornings  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	if _, err := p.Parse("# nothing to run"); err != ErrEmptySpec {
+		t.Errorf("expected ErrEmptySpec for a comment-only spec, got %v", err)
+	}
+	if _, err := standardParser.Parse("0 9 * * 1-5 # weekday mornings"); err == nil {
+		t.Error("expected comments to be rejected without the Comments option")
+	}
+
+	// A '#' within a field is not a comment.
+	if s := stripComment("0 9 * * 5#3 # third Friday"); s != "0 9 * * 5#3" {
+		t.Errorf("expected the field to be kept, got %q", s)
+	}
+}
+
+func TestValidateSpec(t *testing.T) {
+	for _, spec := range []string{
+		"0 0 9 * * MON-FRI",
+		"0 0-30/5 * * * ?",
+		"@every 5m",
+		"CRON_TZ=Asia/Tokyo @daily",
+	} {
+		if err := ValidateSpec(spec); err != nil {
+			t.Errorf("%s: unexpected error %v", spec, err)
+		}
+	}
+	for _, spec := range []string{
+		"",
+		"0 0 25 * * ?",
+		"0 0 9 *",
+		"@every -5m",
+		"@fortnightly",
+		"CRON_TZ=Nowhere/Special @daily",
+	} {
+		_, expected := Parse(spec)
+		err := ValidateSpec(spec)
+		if err == nil || expected == nil || err.Error() != expected.Error() {
+			t.Errorf("%s: expected the error of Parse %v, got %v", spec, expected, err)
+		}
+	}
+}
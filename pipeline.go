@@ -0,0 +1,50 @@
+package cron
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Pipeline is a Job that runs a sequence of steps in order, each starting only
+// after the previous one has completed. If a step returns an error, the
+// remaining steps are skipped and OnError is called with the index of the
+// failed step and its error.
+//
+// Runs of a Pipeline never overlap: if it is activated again while a previous
+// run is still in progress, the new run waits for it to complete, so steps
+// stay in strict sequence across activations.
+type Pipeline struct {
+	Steps   []func() error
+	OnError func(step int, err error)
+
+	mu sync.Mutex
+}
+
+// Run runs the steps of the pipeline in order, stopping at the first error.
+func (p *Pipeline) Run() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, step := range p.Steps {
+		if err := step(); err != nil {
+			if p.OnError != nil {
+				p.OnError(i, err)
+			}
+			return
+		}
+	}
+}
+
+// AddPipeline adds a Pipeline of the given steps to the Cron, to be run on the
+// given schedule. A failing step aborts the run and is logged; use AddNameJob
+// with a Pipeline to handle failures with a callback instead.
+func (c *Cron) AddPipeline(name, spec string, steps ...func() error) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("cron: pipeline %q has no steps", name)
+	}
+	return c.AddNameJob(name, spec, &Pipeline{
+		Steps: steps,
+		OnError: func(step int, err error) {
+			c.logf("cron: pipeline %q aborted at step %d: %v", name, step, err)
+		},
+	})
+}
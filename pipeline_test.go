@@ -0,0 +1,105 @@
+package cron
+
+import (
+	"errors"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPipeline(t *testing.T) {
+	var ran []string
+	step := func(name string, err error) func() error {
+		return func() error {
+			ran = append(ran, name)
+			return err
+		}
+	}
+
+	p := &Pipeline{Steps: []func() error{step("extract", nil), step("transform", nil), step("load", nil)}}
+	p.Run()
+	if expected := []string{"extract", "transform", "load"}; !reflect.DeepEqual(ran, expected) {
+		t.Errorf("expected steps %v, got %v", expected, ran)
+	}
+
+	ran = nil
+	failure := errors.New("bad input")
+	var failed int
+	var failedErr error
+	p = &Pipeline{
+		Steps:   []func() error{step("extract", nil), step("transform", failure), step("load", nil)},
+		OnError: func(step int, err error) { failed, failedErr = step, err },
+	}
+	p.Run()
+	if expected := []string{"extract", "transform"}; !reflect.DeepEqual(ran, expected) {
+		t.Errorf("expected steps %v, got %v", expected, ran)
+	}
+	if failed != 1 || failedErr != failure {
+		t.Errorf("expected step 1 to fail with %v, got step %d with %v", failure, failed, failedErr)
+	}
+}
+
+// Test that runs of a pipeline do not overlap.
+func TestPipelineDoesNotOverlap(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		running int
+		overlap bool
+	)
+	slow := func() error {
+		mu.Lock()
+		running++
+		overlap = overlap || running > 1
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	}
+
+	p := &Pipeline{Steps: []func() error{slow, slow}}
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Run()
+		}()
+	}
+	wg.Wait()
+	if overlap {
+		t.Error("expected pipeline runs not to overlap")
+	}
+}
+
+func TestAddPipeline(t *testing.T) {
+	var buf syncWriter
+	done := make(chan struct{}, 10)
+	cron := New()
+	cron.ErrorLog = log.New(&buf, "", 0)
+	if err := cron.AddPipeline("empty", "* * * * * ?"); err == nil {
+		t.Error("expected an error for a pipeline without steps")
+	}
+	err := cron.AddPipeline("nightly", "* * * * * ?",
+		func() error { return errors.New("no data") },
+		func() error { done <- struct{}{}; return nil },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cron.Start()
+	defer cron.Stop()
+
+	select {
+	case <-done:
+		t.Fatal("expected the pipeline to abort at the failing step")
+	case <-time.After(OneSecond):
+	}
+	if !strings.Contains(buf.String(), `pipeline "nightly" aborted at step 0: no data`) {
+		t.Errorf("expected the failure to be logged, got %q", buf.String())
+	}
+}
@@ -0,0 +1,72 @@
+package cron
+
+import "time"
+
+// VisitSchedule calls visit for each activation of the schedule that falls
+// after from and no later than to, in order. It works with any Schedule,
+// including custom ones.
+//
+// Iteration stops early if the schedule becomes unsatisfiable (returns the
+// zero time) or fails to advance past the previous activation, so a faulty
+// Schedule cannot cause an infinite loop.
+func VisitSchedule(s Schedule, from, to time.Time, visit func(t time.Time)) {
+	t := from
+	for {
+		next := s.Next(t)
+		if next.IsZero() || !next.After(t) || next.After(to) {
+			return
+		}
+		visit(next)
+		t = next
+	}
+}
+
+// EntriesOnDate returns a snapshot of the entries that are scheduled to run at
+// least once during the calendar day of date, in the Cron's time zone, or in
+// the entry's own for an entry with a Location. Like ExportICS, it uses the
+// schedule's Next, so random delays are not reflected.
+func (c *Cron) EntriesOnDate(date time.Time) []*Entry {
+	cronLoc := c.Location()
+	entries := []*Entry{}
+	for _, e := range c.Entries() {
+		loc := cronLoc
+		if e.Location != nil {
+			loc = e.Location
+		}
+		y, m, d := date.In(loc).Date()
+		start := time.Date(y, m, d, 0, 0, 0, 0, loc)
+		end := start.AddDate(0, 0, 1)
+		next := e.Schedule.Next(start.Add(-time.Nanosecond))
+		if !next.IsZero() && next.Before(end) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// maxCountedRuns bounds the number of activations CountRuns iterates over, so
+// that a high-frequency schedule over a long period cannot stall the caller.
+const maxCountedRuns = 1 << 20
+
+// CountRuns returns the number of times the named entry is scheduled to run
+// after from and no later than to. Counting stops at 1<<20 activations. ok is
+// false if no entry has the name.
+func (c *Cron) CountRuns(name string, from, to time.Time) (n int, ok bool) {
+	var s Schedule
+	c.do(func() {
+		if i := pos(c.entries, name); i != -1 {
+			s = c.entries[i].Schedule
+		}
+	})
+	if s == nil {
+		return 0, false
+	}
+	for t := from; n < maxCountedRuns; n++ {
+		next := s.Next(t)
+		if next.IsZero() || !next.After(t) || next.After(to) {
+			break
+		}
+		t = next
+	}
+	return n, true
+}
@@ -0,0 +1,114 @@
+package cron
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestVisitSchedule(t *testing.T) {
+	sched, err := Parse("0 0 */6 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []time.Time
+	VisitSchedule(sched, getTime("Mon Jul 9 00:00 2012"), getTime("Tue Jul 10 00:00 2012"),
+		func(t time.Time) { actual = append(actual, t) })
+
+	expected := []string{
+		"Mon Jul 9 06:00 2012",
+		"Mon Jul 9 12:00 2012",
+		"Mon Jul 9 18:00 2012",
+		"Tue Jul 10 00:00 2012",
+	}
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d activations, got %d: %v", len(expected), len(actual), actual)
+	}
+	for i, e := range expected {
+		if !actual[i].Equal(getTime(e)) {
+			t.Errorf("activation %d: (expected) %s != %s (actual)", i, e, actual[i])
+		}
+	}
+}
+
+// stuckSchedule always returns the same time, which would loop forever if
+// VisitSchedule did not guard against it.
+type stuckSchedule struct{ at time.Time }
+
+func (s stuckSchedule) Next(time.Time) time.Time            { return s.at }
+func (s stuckSchedule) RandomNext(time.Time, int) time.Time { return s.at }
+
+func TestVisitScheduleStopsWhenNotAdvancing(t *testing.T) {
+	at := getTime("Mon Jul 9 12:00 2012")
+	calls := 0
+	VisitSchedule(stuckSchedule{at}, getTime("Mon Jul 9 00:00 2012"), getTime("Tue Jul 10 00:00 2012"),
+		func(time.Time) { calls++ })
+	if calls != 1 {
+		t.Errorf("expected 1 visit, got %d", calls)
+	}
+}
+
+func TestVisitScheduleUnsatisfiable(t *testing.T) {
+	calls := 0
+	VisitSchedule(new(ZeroSchedule), getTime("Mon Jul 9 00:00 2012"), getTime("Tue Jul 10 00:00 2012"),
+		func(time.Time) { calls++ })
+	if calls != 0 {
+		t.Errorf("expected no visits, got %d", calls)
+	}
+}
+
+func TestEntriesOnDate(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("America/New_York not available:", err)
+	}
+
+	cron := NewWithLocation(loc)
+	cron.AddNameFunc("midnight", "0 0 0 * * ?", func() {})
+	cron.AddNameFunc("mondays", "0 30 23 * * MON", func() {})
+	cron.AddNameFunc("first", "0 0 12 1 * ?", func() {})
+	// Tuesdays in UTC, which it already is there, but not in New York.
+	cron.AddFuncInLocation("utc-tuesdays", "0 0 12 * * TUE", time.UTC, func() {})
+	cron.AddFuncInLocation("utc-mondays", "0 0 12 * * MON", time.UTC, func() {})
+
+	// 2012-07-10 02:00 UTC is still Monday July 9 in New York.
+	date := time.Date(2012, time.July, 10, 2, 0, 0, 0, time.UTC)
+	var names []string
+	for _, e := range cron.EntriesOnDate(date) {
+		names = append(names, e.Name)
+	}
+	sort.Strings(names)
+	if expected := []string{"midnight", "mondays", "utc-tuesdays"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestCountRuns(t *testing.T) {
+	cron := New()
+	cron.AddNameFunc("daily", "0 0 9 * * ?", func() {})
+	cron.AddNameFunc("minutely", "0 * * * * ?", func() {})
+	cron.AddNameFunc("secondly", "* * * * * ?", func() {})
+
+	from := getTime("Mon Jul 9 00:00 2012")
+	week := from.AddDate(0, 0, 7)
+	entries := []struct {
+		name     string
+		to       time.Time
+		expected int
+	}{
+		{"daily", week, 7},
+		{"minutely", week, 7 * 24 * 60},
+		{"secondly", from.AddDate(0, 0, 30), maxCountedRuns},
+	}
+	for _, c := range entries {
+		n, ok := cron.CountRuns(c.name, from, c.to)
+		if !ok || n != c.expected {
+			t.Errorf("%s: expected %d runs, got %d (ok %v)", c.name, c.expected, n, ok)
+		}
+	}
+	if _, ok := cron.CountRuns("missing", from, week); ok {
+		t.Error("expected ok to be false for an unknown name")
+	}
+}
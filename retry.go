@@ -0,0 +1,102 @@
+package cron
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// maxPendingRetries bounds the retries waiting in the retry queue of a Cron,
+// so that a storm of failing jobs cannot grow it without limit. Retries over
+// the limit are dropped and logged.
+const maxPendingRetries = 1024
+
+// retry is a pending retry of a failed run of an ErrorJob.
+type retry struct {
+	at      time.Time       // when the retry is due
+	ctx     context.Context // the context of the run that failed
+	name    string
+	job     Job
+	done    int // the number of retries already run
+	max     int
+	backoff time.Duration
+}
+
+// retryQueue is a heap of pending retries, soonest first. It is kept apart
+// from the entries so that retries neither reorder nor delay them; the
+// scheduler only merges the soonest retry into its timer.
+type retryQueue []*retry
+
+func (q retryQueue) Len() int            { return len(q) }
+func (q retryQueue) Less(i, j int) bool  { return q[i].at.Before(q[j].at) }
+func (q retryQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *retryQueue) Push(x interface{}) { *q = append(*q, x.(*retry)) }
+func (q *retryQueue) Pop() interface{} {
+	old := *q
+	r := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return r
+}
+
+// PendingRetries returns the number of retries of failed runs waiting in the
+// retry queue for their backoff to elapse.
+func (c *Cron) PendingRetries() int {
+	var n int
+	c.do(func() { n = len(c.retries) })
+	return n
+}
+
+// retryLater queues r after its backoff, doubled for every retry already
+// run, or gives it up if it has no retry left, its Cron has been stopped
+// since the run, or the queue is full. It is called by the goroutine of the
+// run that failed.
+func (c *Cron) retryLater(r *retry) {
+	if r.done >= r.max {
+		c.logf("cron: job %q gave up after %d retries", r.name, r.max)
+		return
+	}
+	if r.ctx.Err() != nil {
+		return
+	}
+	r.at = c.now().Add(r.backoff << uint(r.done))
+	full := false
+	c.do(func() {
+		if full = len(c.retries) >= maxPendingRetries; !full {
+			heap.Push(&c.retries, r)
+		}
+	})
+	if full {
+		c.logf("cron: retry queue full, dropping retry of %q", r.name)
+	}
+}
+
+// runRetries starts the retries due by cutoff, each in its own goroutine or
+// through the dispatcher like a job. They are all taken off the queue first,
+// as a retry that fails again is queued by its own goroutine.
+func (c *Cron) runRetries(cutoff time.Time) {
+	var due []*retry
+	for len(c.retries) > 0 && !c.retries[0].at.After(cutoff) {
+		due = append(due, heap.Pop(&c.retries).(*retry))
+	}
+	for _, r := range due {
+		r := r
+		if r.ctx.Err() != nil {
+			continue
+		}
+		c.jobs.Add(1)
+		sem := c.sem
+		run := func() {
+			defer c.jobs.Done()
+			if err := c.attempt(r.ctx, sem, r.name, r.job); err != nil {
+				r.done++
+				c.retryLater(r)
+			}
+		}
+		if c.dispatch != nil {
+			c.dispatch(run)
+			continue
+		}
+		go run()
+	}
+}
@@ -0,0 +1,194 @@
+package cron
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// SharedScheduler multiplexes many logical Crons onto a single goroutine and
+// timer. It is meant for deployments with large numbers of independent
+// schedulers, e.g. one Cron per tenant, where a goroutine and timer per Cron
+// would not scale.
+//
+// Each Cron returned by the Cron method behaves like an independent Cron:
+// entries, names, Start and Stop are scoped to its owner. The shared goroutine
+// is started when the first owner starts and exits when the last one stops.
+// Started owners are kept in a heap ordered by their soonest activation, so
+// each wakeup only visits the owners that are due.
+type SharedScheduler struct {
+	mu      sync.Mutex
+	crons   map[string]*Cron
+	owners  byNext
+	wake    chan struct{}
+	running bool
+}
+
+// NewShared returns a new SharedScheduler with no owners.
+func NewShared() *SharedScheduler {
+	return &SharedScheduler{
+		crons: make(map[string]*Cron),
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// Cron returns the logical Cron for owner, in the Local time zone, creating it
+// on first use. Repeated calls with the same owner return the same Cron.
+func (s *SharedScheduler) Cron(owner string) *Cron {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.crons[owner]
+	if !ok {
+		c = New()
+		c.shared = s
+		s.crons[owner] = c
+	}
+	return c
+}
+
+// update runs fn while holding the lock, then repositions c among the started
+// owners. The loop is woken up only if c is now the soonest owner, as changes
+// to the others cannot make it run any earlier.
+func (s *SharedScheduler) update(c *Cron, fn func()) {
+	s.mu.Lock()
+	fn()
+	soonest := false
+	if c.running {
+		heap.Fix(&s.owners, c.slot)
+		soonest = c.slot == 0
+	}
+	s.mu.Unlock()
+	if soonest {
+		s.wakeup()
+	}
+}
+
+// wakeup asks the loop to re-evaluate its timer, without blocking.
+func (s *SharedScheduler) wakeup() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// start begins scheduling the entries of c, starting the loop if needed.
+func (s *SharedScheduler) start(c *Cron) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.running {
+		return
+	}
+	c.running = true
+	now := c.now()
+	for _, e := range c.entries {
+		c.scheduleNext(e, now)
+	}
+	heap.Init(c.queue())
+	heap.Push(&s.owners, c)
+	if !s.running {
+		s.running = true
+		go s.run()
+		return
+	}
+	s.wakeup()
+}
+
+// stop stops scheduling the entries of c, then waits for its running jobs and
+// runs its shutdown jobs as Cron.stopWith does. ok is false if c was not
+// started.
+func (s *SharedScheduler) stop(c *Cron, wait time.Duration) (ok bool, err error) {
+	var shutdown []*Entry
+	s.mu.Lock()
+	if c.running {
+		c.running, ok = false, true
+		shutdown = c.shutdown
+		c.retries = nil
+		heap.Remove(&s.owners, c.slot)
+	}
+	s.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	c.cancelJobs()
+	s.wakeup()
+	if wait >= 0 {
+		err = c.waitJobs(wait)
+	}
+	c.runShutdownJobs(shutdown)
+	return true, err
+}
+
+// run is the shared scheduler loop. It runs the owners that are due, soonest
+// first, then sleeps until the soonest activation among all of them.
+func (s *SharedScheduler) run() {
+	for {
+		s.mu.Lock()
+		if len(s.owners) == 0 {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+		for {
+			c := s.owners[0]
+			next := c.soonest()
+			if next.IsZero() || next.After(c.now().Add(c.early)) {
+				break
+			}
+			c.runDue(c.now())
+			heap.Fix(&s.owners, 0)
+		}
+		root := s.owners[0]
+		next, now := root.soonest(), root.now()
+		s.mu.Unlock()
+
+		timer := timerUntil(next, now)
+		select {
+		case <-timer.C:
+		case <-s.wake:
+			timer.Stop()
+		}
+	}
+}
+
+// soonest returns the time of the entry or retry of c that runs next, or the
+// zero time if none is scheduled.
+func (c *Cron) soonest() time.Time {
+	var next time.Time
+	if len(c.entries) > 0 {
+		next = c.entries[0].Next
+	}
+	if len(c.retries) > 0 && (next.IsZero() || c.retries[0].at.Before(next)) {
+		next = c.retries[0].at
+	}
+	return next
+}
+
+// byNext is a heap of the started owners of a SharedScheduler, ordered by
+// their soonest activation. Owners with nothing scheduled sort last.
+type byNext []*Cron
+
+func (h byNext) Len() int { return len(h) }
+func (h byNext) Less(i, j int) bool {
+	a, b := h[i].soonest(), h[j].soonest()
+	if a.IsZero() {
+		return false
+	}
+	return b.IsZero() || a.Before(b)
+}
+func (h byNext) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].slot, h[j].slot = i, j
+}
+func (h *byNext) Push(x interface{}) {
+	c := x.(*Cron)
+	c.slot = len(*h)
+	*h = append(*h, c)
+}
+func (h *byNext) Pop() interface{} {
+	old := *h
+	n := len(old)
+	c := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return c
+}
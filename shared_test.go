@@ -0,0 +1,128 @@
+package cron
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Test that owners of a shared scheduler have independent entries and names.
+func TestSharedSchedulerOwners(t *testing.T) {
+	s := NewShared()
+	a, b := s.Cron("a"), s.Cron("b")
+	if s.Cron("a") != a {
+		t.Fatal("expected the same Cron for the same owner")
+	}
+
+	wgA, wgB := &sync.WaitGroup{}, &sync.WaitGroup{}
+	wgA.Add(1)
+	wgB.Add(1)
+	a.AddNameFunc("job", "* * * * * ?", func() { wgA.Done() })
+	b.AddNameFunc("job", "* * * * * ?", func() { wgB.Done() })
+	a.AddNameFunc("yearly", "0 0 0 1 1 ?", func() {})
+	a.Start()
+	b.Start()
+	defer a.Stop()
+	defer b.Stop()
+
+	for name, wg := range map[string]*sync.WaitGroup{"a": wgA, "b": wgB} {
+		select {
+		case <-time.After(OneSecond):
+			t.Fatalf("expected the job of owner %s to run", name)
+		case <-wait(wg):
+		}
+	}
+
+	if n := len(a.Entries()); n != 2 {
+		t.Errorf("expected 2 entries for owner a, got %d", n)
+	}
+	if n := len(b.Entries()); n != 1 {
+		t.Errorf("expected 1 entry for owner b, got %d", n)
+	}
+
+	b.RemoveJob("job")
+	if n := len(b.Entries()); n != 0 {
+		t.Errorf("expected owner b to be empty, got %d entries", n)
+	}
+	if n := len(a.Entries()); n != 2 {
+		t.Errorf("expected owner a to keep 2 entries, got %d", n)
+	}
+}
+
+// Test that stopping one owner does not affect the others.
+func TestSharedSchedulerStopOwner(t *testing.T) {
+	s := NewShared()
+	a, b := s.Cron("a"), s.Cron("b")
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	count := func(owner string) func() {
+		return func() {
+			mu.Lock()
+			calls[owner]++
+			mu.Unlock()
+		}
+	}
+	a.AddFunc("* * * * * ?", count("a"))
+	b.AddFunc("* * * * * ?", count("b"))
+	a.Start()
+	b.Start()
+	a.Stop()
+	defer b.Stop()
+
+	<-time.After(OneSecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if calls["a"] != 0 {
+		t.Errorf("expected stopped owner not to run, ran %d times", calls["a"])
+	}
+	if calls["b"] != 1 {
+		t.Errorf("expected running owner to run once, ran %d times", calls["b"])
+	}
+}
+
+// Test that many owners share a single goroutine.
+func TestSharedSchedulerSingleGoroutine(t *testing.T) {
+	s := NewShared()
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		c := s.Cron(string(rune('a' + i)))
+		c.AddFunc("0 0 0 1 1 ?", func() {})
+		c.Start()
+		defer c.Stop()
+	}
+	if delta := runtime.NumGoroutine() - before; delta > 1 {
+		t.Errorf("expected at most one extra goroutine, got %d", delta)
+	}
+}
+
+// Test that started owners are ordered by their soonest activation, and that
+// adding an entry repositions its owner.
+func TestSharedSchedulerOwnerHeap(t *testing.T) {
+	s := NewShared()
+	yearly, daily, idle := s.Cron("yearly"), s.Cron("daily"), s.Cron("idle")
+	yearly.AddFunc("0 0 0 1 1 ?", func() {})
+	daily.AddFunc("0 0 0 * * ?", func() {})
+	for _, c := range []*Cron{idle, yearly, daily} {
+		c.Start()
+		defer c.Stop()
+	}
+
+	root := func() *Cron {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.owners[0]
+	}
+	if root() != daily {
+		t.Fatal("expected the owner with the soonest activation at the root")
+	}
+	idle.AddFunc("* * * * * ?", func() {})
+	if root() != idle {
+		t.Error("expected an owner to move to the root once it runs soonest")
+	}
+	idle.Stop()
+	if root() != daily {
+		t.Error("expected a stopped owner to leave the heap")
+	}
+}
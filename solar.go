@@ -0,0 +1,120 @@
+package cron
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// SolarEvent is a daily solar event that a Solar schedule activates on.
+type SolarEvent int
+
+const (
+	Sunrise SolarEvent = iota // The upper limb of the sun appears over the horizon
+	Sunset                    // The upper limb of the sun disappears below the horizon
+)
+
+// String returns "sunrise" or "sunset".
+func (e SolarEvent) String() string {
+	if e == Sunrise {
+		return "sunrise"
+	}
+	return "sunset"
+}
+
+// solarSchedule activates at a solar event, plus an offset, at a location.
+type solarSchedule struct {
+	lat, lon float64
+	event    SolarEvent
+	offset   time.Duration
+}
+
+// Solar returns a schedule that activates daily at sunrise or sunset at the
+// given latitude and longitude, in degrees north and east, plus offset, e.g.
+// Solar(48.85, 2.35, Sunset, 30*time.Minute) for half an hour after sunset in
+// Paris. Times follow the NOAA sunrise equation, which is accurate to about a
+// minute; activations are truncated to the second.
+//
+// Days on which the event does not occur, as during polar day or night, are
+// skipped. It panics if lat is not within -90 to 90 or lon within -180 to 180.
+func Solar(lat, lon float64, event SolarEvent, offset time.Duration) Schedule {
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 || (event != Sunrise && event != Sunset) {
+		panic("cron: Solar requires lat in -90-90, lon in -180-180 and a valid event")
+	}
+	return &solarSchedule{lat, lon, event, offset}
+}
+
+// String describes the schedule, e.g. "sunset+30m0s at 48.85,2.35".
+func (s *solarSchedule) String() string {
+	str := s.event.String()
+	if s.offset > 0 {
+		str += "+" + s.offset.String()
+	} else if s.offset < 0 {
+		str += s.offset.String()
+	}
+	return fmt.Sprintf("%s at %g,%g", str, s.lat, s.lon)
+}
+
+// Julian date of the Unix epoch and of the J2000 epoch.
+const (
+	julianUnixEpoch = 2440587.5
+	julianJ2000     = 2451545.0
+)
+
+// Next returns the first activation after t, in t's location, or the zero time
+// if the event does not occur within a year and a half.
+func (s *solarSchedule) Next(t time.Time) time.Time {
+	// Start early enough that an event whose offset brings it past t is not
+	// missed: a day, plus as many days as a positive offset spans.
+	back := 1.0
+	if s.offset > 0 {
+		back += math.Ceil(float64(s.offset) / float64(24*time.Hour))
+	}
+	day := math.Ceil(float64(t.Unix())/86400+julianUnixEpoch-julianJ2000+0.0008) - back
+	for i := 0; i < 550+int(back); i++ {
+		next, ok := s.on(day + float64(i))
+		if !ok {
+			continue
+		}
+		if next = next.Add(s.offset).Truncate(time.Second); next.After(t) {
+			return next.In(t.Location())
+		}
+	}
+	return time.Time{}
+}
+
+// RandomNext delays the next solar activation by up to delayRange seconds.
+func (s *solarSchedule) RandomNext(t time.Time, delayRange int) time.Time {
+	return jitter(s, t, delayRange, cryptoIntn)
+}
+
+// on returns the time of the event on the given day, counted in days since
+// J2000, or false if the sun does not rise or set that day.
+func (s *solarSchedule) on(day float64) (time.Time, bool) {
+	const rad = math.Pi / 180
+
+	// Mean solar noon, solar mean anomaly, and equation of the center.
+	noon := day - s.lon/360
+	m := math.Mod(357.5291+0.98560028*noon, 360)
+	c := 1.9148*math.Sin(m*rad) + 0.0200*math.Sin(2*m*rad) + 0.0003*math.Sin(3*m*rad)
+
+	// Ecliptic longitude, solar transit and declination of the sun.
+	lambda := math.Mod(m+c+180+102.9372, 360)
+	transit := julianJ2000 + noon + 0.0053*math.Sin(m*rad) - 0.0069*math.Sin(2*lambda*rad)
+	sinDecl := math.Sin(lambda*rad) * math.Sin(23.4397*rad)
+	cosDecl := math.Cos(math.Asin(sinDecl))
+
+	// Hour angle of the event, accounting for refraction and the solar disc.
+	cosHour := (math.Sin(-0.833*rad) - math.Sin(s.lat*rad)*sinDecl) / (math.Cos(s.lat*rad) * cosDecl)
+	if cosHour < -1 || cosHour > 1 {
+		return time.Time{}, false
+	}
+	hour := math.Acos(cosHour) / rad
+
+	julian := transit - hour/360
+	if s.event == Sunset {
+		julian = transit + hour/360
+	}
+	seconds := (julian - julianUnixEpoch) * 86400
+	return time.Unix(0, int64(seconds*1e9)).UTC(), true
+}
@@ -0,0 +1,81 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSolar(t *testing.T) {
+	entries := []struct {
+		name     string
+		lat, lon float64
+		event    SolarEvent
+		from     time.Time
+		expected time.Time
+	}{
+		// Published times, in UTC, for the summer solstice in London and the
+		// winter solstice in New York and Sydney.
+		{"London sunrise", 51.5074, -0.1278, Sunrise,
+			time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 21, 3, 43, 0, 0, time.UTC)},
+		{"London sunset", 51.5074, -0.1278, Sunset,
+			time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 21, 20, 21, 0, 0, time.UTC)},
+		{"New York sunrise", 40.7128, -74.0060, Sunrise,
+			time.Date(2024, 12, 21, 5, 0, 0, 0, time.UTC), time.Date(2024, 12, 21, 12, 16, 0, 0, time.UTC)},
+		{"New York sunset", 40.7128, -74.0060, Sunset,
+			time.Date(2024, 12, 21, 5, 0, 0, 0, time.UTC), time.Date(2024, 12, 21, 21, 32, 0, 0, time.UTC)},
+		{"Sydney sunrise", -33.8688, 151.2093, Sunrise,
+			time.Date(2024, 6, 20, 12, 0, 0, 0, time.UTC), time.Date(2024, 6, 20, 20, 59, 0, 0, time.UTC)},
+	}
+
+	for _, c := range entries {
+		next := Solar(c.lat, c.lon, c.event, 0).Next(c.from)
+		if d := next.Sub(c.expected); d < -2*time.Minute || d > 2*time.Minute {
+			t.Errorf("%s: expected about %v, got %v", c.name, c.expected, next)
+		}
+	}
+}
+
+func TestSolarOffsetAndLocation(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("Europe/London not available:", err)
+	}
+	sunset := Solar(51.5074, -0.1278, Sunset, 0)
+	later := Solar(51.5074, -0.1278, Sunset, 30*time.Minute)
+
+	from := time.Date(2024, 6, 21, 12, 0, 0, 0, loc)
+	next := later.Next(from)
+	if next.Location() != loc {
+		t.Errorf("expected the activation in %v, got %v", loc, next.Location())
+	}
+	if d := next.Sub(sunset.Next(from)); d != 30*time.Minute {
+		t.Errorf("expected the offset to be applied, got %v", d)
+	}
+
+	// The following activation is on the next day.
+	if d := later.Next(next).Sub(next); d < 23*time.Hour || d > 25*time.Hour {
+		t.Errorf("expected the following activation a day later, got %v", d)
+	}
+}
+
+// Test that days without a sunset, during polar day, are skipped.
+// Test that an offset of more than a day still yields the first activation
+// after t, which belongs to an event two days before it.
+func TestSolarLongOffset(t *testing.T) {
+	sunset := Solar(51.5074, -0.1278, Sunset, 0)
+	later := Solar(51.5074, -0.1278, Sunset, 36*time.Hour)
+
+	from := time.Date(2024, 6, 21, 12, 0, 0, 0, time.UTC)
+	expected := sunset.Next(from.Add(-36 * time.Hour)).Add(36 * time.Hour)
+	if next := later.Next(from); !next.Equal(expected) {
+		t.Errorf("expected %v, got %v", expected, next)
+	}
+}
+
+func TestSolarPolar(t *testing.T) {
+	from := time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC)
+	next := Solar(78.2232, 15.6267, Sunset, 0).Next(from)
+	if next.IsZero() || next.Month() != time.August {
+		t.Errorf("expected the first sunset after the midnight sun in August, got %v", next)
+	}
+}
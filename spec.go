@@ -3,6 +3,8 @@ package cron
 import (
 	"crypto/rand"
 	"math/big"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -10,6 +12,29 @@ import (
 // traditional crontab specification. It is computed initially and stored as bit sets.
 type SpecSchedule struct {
 	Second, Minute, Hour, Dom, Month, Dow uint64
+
+	// LastDom additionally matches the last day of each month, whichever
+	// day that is.
+	LastDom bool
+
+	// NearestWeekday additionally matches, for each day of the month whose
+	// bit is set, the weekday nearest to it within the same month: the
+	// Friday before a Saturday or the Monday after a Sunday, except that the
+	// 1st and the last day move the other way rather than into another month.
+	NearestWeekday uint64
+
+	// LastDow additionally matches the last occurrence within the month of
+	// each day of the week whose bit is set, e.g. the last Friday.
+	LastDow uint64
+
+	// NthDow additionally matches the nth occurrence within the month of a
+	// day of the week, for n from 1 to 5: bit 8*weekday+n is set for the nth
+	// occurrence of that weekday, e.g. 8*2+2 for the second Tuesday.
+	NthDow uint64
+
+	// DayAnd requires both the day of month and the day of week to match when
+	// both are restricted, instead of either one as in standard cron.
+	DayAnd bool
 }
 
 // bounds provides a range of acceptable values (plus a map of name to value).
@@ -54,13 +79,175 @@ const (
 	starBit = 1 << 63
 )
 
+// EveryNHoursFrom returns a schedule that activates on the hour every
+// stepHours hours, counting from startHour and wrapping around midnight. For
+// example EveryNHoursFrom(5, 4) activates at 01:00, 05:00, 09:00, 13:00, 17:00
+// and 21:00, whereas the spec "0 0 5/4 * * *" stops at 21:00 and never fires at
+// 01:00. Every day uses the same hours, so if stepHours does not divide 24 the
+// gap across midnight is shorter than the others.
+//
+// It panics if startHour is not within 0-23 or stepHours within 1-24.
+func EveryNHoursFrom(startHour, stepHours int) *SpecSchedule {
+	if startHour < 0 || startHour > 23 || stepHours < 1 || stepHours > 24 {
+		panic("cron: EveryNHoursFrom requires startHour in 0-23 and stepHours in 1-24")
+	}
+	var hourBits uint64
+	for offset := 0; offset < 24; offset += stepHours {
+		hourBits |= 1 << uint((startHour+offset)%24)
+	}
+	return &SpecSchedule{
+		Second: 1 << seconds.min,
+		Minute: 1 << minutes.min,
+		Hour:   hourBits,
+		Dom:    all(dom),
+		Month:  all(months),
+		Dow:    all(dow),
+	}
+}
+
+// FirstOfMonth returns a schedule that activates once a month, at hour:min on
+// the first day. It panics if hour or min is out of range.
+func FirstOfMonth(hour, min int) *SpecSchedule {
+	s := monthly(hour, min)
+	s.Dom = 1 << dom.min
+	return s
+}
+
+// LastOfMonth returns a schedule that activates once a month, at hour:min on
+// the last calendar day (the 28th to the 31st, depending on the month). It
+// panics if hour or min is out of range.
+func LastOfMonth(hour, min int) *SpecSchedule {
+	s := monthly(hour, min)
+	s.LastDom = true
+	return s
+}
+
+// monthly returns a schedule activating at hour:min on no day of the month.
+func monthly(hour, min int) *SpecSchedule {
+	if hour < 0 || hour > 23 || min < 0 || min > 59 {
+		panic("cron: hour must be in 0-23 and min in 0-59")
+	}
+	return &SpecSchedule{
+		Second: 1 << seconds.min,
+		Minute: 1 << uint(min),
+		Hour:   1 << uint(hour),
+		Month:  all(months),
+		Dow:    all(dow),
+	}
+}
+
+// String returns the schedule as a spec in the seconds-first format of Parse,
+// e.g. "0 0-30/5 9-17/2 * * 1-5". Fields are written with numbers rather than
+// names, and descriptors such as "@daily" come out as the fields they stand
+// for. DayAnd is an option of the parser rather than part of the spec, so it
+// is not represented.
+func (s *SpecSchedule) String() string {
+	var (
+		dayOfMonth = fieldString(s.Dom, dom)
+		dayOfWeek  = fieldString(s.Dow, dow)
+	)
+	if s.LastDom {
+		dayOfMonth = append(dayOfMonth, "L")
+	}
+	for d := dom.min; d <= dom.max; d++ {
+		if 1<<d&s.NearestWeekday > 0 {
+			dayOfMonth = append(dayOfMonth, strconv.Itoa(int(d))+"W")
+		}
+	}
+	for d := dow.min; d <= dow.max; d++ {
+		if 1<<d&s.LastDow > 0 {
+			dayOfWeek = append(dayOfWeek, strconv.Itoa(int(d))+"L")
+		}
+		for n := uint(1); n <= 5; n++ {
+			if 1<<(8*d+n)&s.NthDow > 0 {
+				dayOfWeek = append(dayOfWeek, strconv.Itoa(int(d))+"#"+strconv.Itoa(int(n)))
+			}
+		}
+	}
+	fields := [][]string{
+		fieldString(s.Second, seconds),
+		fieldString(s.Minute, minutes),
+		fieldString(s.Hour, hours),
+		dayOfMonth,
+		fieldString(s.Month, months),
+		dayOfWeek,
+	}
+	spec := make([]string, len(fields))
+	for i, f := range fields {
+		spec[i] = strings.Join(f, ",")
+	}
+	return strings.Join(spec, " ")
+}
+
+// fieldString returns the ranges of a field whose bits are set, the inverse of
+// getField: "*" or "*/step" for a star, and otherwise runs of three or more
+// values with a common step as "low-high" or "low-high/step".
+func fieldString(bits uint64, r bounds) []string {
+	var ranges []string
+	if bits&starBit > 0 {
+		step := uint(1)
+		for ; step <= r.max-r.min; step++ {
+			if star := getBits(r.min, r.max, step); bits&star == star {
+				break
+			}
+		}
+		if star := getBits(r.min, r.max, step); bits&star == star {
+			bits &^= star
+			if step == 1 {
+				ranges = append(ranges, "*")
+			} else {
+				ranges = append(ranges, "*/"+strconv.FormatUint(uint64(step), 10))
+			}
+		}
+	}
+	var values []uint
+	for v := r.min; v <= r.max; v++ {
+		if 1<<v&bits > 0 {
+			values = append(values, v)
+		}
+	}
+	for i := 0; i < len(values); {
+		j := i + 1
+		if j < len(values) {
+			step := values[j] - values[i]
+			for j+1 < len(values) && values[j+1]-values[j] == step {
+				j++
+			}
+			if j-i >= 2 {
+				rng := strconv.Itoa(int(values[i])) + "-" + strconv.Itoa(int(values[j]))
+				if step > 1 {
+					rng += "/" + strconv.Itoa(int(step))
+				}
+				ranges = append(ranges, rng)
+				i = j + 1
+				continue
+			}
+		}
+		ranges = append(ranges, strconv.Itoa(int(values[i])))
+		i++
+	}
+	return ranges
+}
+
 // Next returns the next time this schedule is activated, greater than the given
 // time.  If no time can be found to satisfy the schedule, return the zero time.
 func (s *SpecSchedule) Next(t time.Time) time.Time {
 	return s.RandomNext(t, 0)
 }
 
+// RandomNext returns the next activation time like Next, delayed by a random
+// number of seconds in [0, delayRange). The delayed time never reaches the
+// following activation: it is clamped to one second before it, so a delay
+// range wider than the schedule's interval cannot push a run into the next
+// slot or reorder runs.
 func (s *SpecSchedule) RandomNext(t time.Time, delayRange int) time.Time {
+	return s.randomNext(t, delayRange, cryptoIntn)
+}
+
+// randomNext implements RandomNext, drawing the delay from intn, which returns
+// a number in [0, n). Given a deterministic intn it is a pure function of its
+// arguments, which is what the tests use to check the delay distribution.
+func (s *SpecSchedule) randomNext(t time.Time, delayRange int, intn func(n int64) int64) time.Time {
 	// General approach:
 	// For Month, Day, Hour, Minute, Second:
 	// Check if the time value matches.  If yes, continue to the next field.
@@ -69,8 +256,8 @@ func (s *SpecSchedule) RandomNext(t time.Time, delayRange int) time.Time {
 	// of the field list (since it is necessary to re-verify previous field
 	// values)
 
-	if delayRange < 0 || delayRange > 82800 {
-		panic("delayRange cannot exceed 0-82800 second.（24H）")
+	if delayRange < 0 {
+		panic("cron: negative delay range")
 	}
 
 	// Start at the earliest possible time (the upcoming second).
@@ -122,8 +309,14 @@ WRAP:
 			added = true
 			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
 		}
+		prev, skipped := t.Hour(), uint(t.Hour()+1)%24
 		t = t.Add(1 * time.Hour)
 
+		// When clocks spring forward, the hour skipped does not exist: its
+		// activations move forward to the hour after the gap.
+		if t.Hour() != prev && uint(t.Hour()) != skipped && 1<<skipped&s.Hour > 0 {
+			break
+		}
 		if t.Hour() == 0 {
 			goto WRAP
 		}
@@ -153,30 +346,126 @@ WRAP:
 		}
 	}
 
+	// When clocks fall back, the hour repeated occurs twice: a schedule at
+	// fixed hours is activated in the first occurrence only, while one that
+	// runs every hour goes on through both.
+	if s.Hour&^starBit != getBits(hours.min, hours.max, 1) && isRepeated(t) {
+		added = true
+		t = t.Add(1 * time.Second)
+		goto WRAP
+	}
+
 	if delayRange > 0 {
 		// 生成伪随机数[0,delaySeconds)
 		//rand.NewSource(time.Now().Unix()) // 协程不安全
 		//delaySecond := rand.Intn(delayRange) //  只返回正数
-		delaySecond, _ := rand.Int(rand.Reader, big.NewInt(int64(delayRange)))
-		//o := t
-		t = t.Add(time.Second * time.Duration(delaySecond.Int64()))
-		//fmt.Printf("范围：%v  随机秒数：%v  旧时间：%v  新时间：%v \n", delayRange, delaySecond,
-		//	o.Format("2016-01-02 15:04:05"),
-		//	t.Format("2016-01-02 15:04:05"))
+		t = delayBefore(t, s.Next(t), intn(int64(delayRange)))
 	}
 
 	return t
 }
 
+// delayBefore returns t delayed by the given number of seconds, clamped to one
+// second before the following activation unless that is the zero time.
+func delayBefore(t, following time.Time, seconds int64) time.Time {
+	delayed := t.Add(time.Second * time.Duration(seconds))
+	if !following.IsZero() && !delayed.Before(following) {
+		delayed = following.Add(-time.Second)
+	}
+	return delayed
+}
+
+// jitter returns the first activation of s after t, delayed by a number of
+// seconds in [0, delayRange) drawn from intn, but never reaching the following
+// activation. It implements RandomNext for schedules that have no cheaper way
+// to find the following activation than a second call to Next.
+func jitter(s Schedule, t time.Time, delayRange int, intn func(n int64) int64) time.Time {
+	next := s.Next(t)
+	if delayRange <= 0 || next.IsZero() {
+		return next
+	}
+	return delayBefore(next, s.Next(next), intn(int64(delayRange)))
+}
+
+// sourcedSchedule is implemented by schedules whose RandomNext does more than
+// jitter, so that their delay can still be drawn from a given source.
+type sourcedSchedule interface {
+	randomNext(t time.Time, delayRange int, intn func(n int64) int64) time.Time
+}
+
+// randomNextFrom returns the activation s.RandomNext would, with the delay
+// drawn from intn instead of crypto/rand.
+func randomNextFrom(s Schedule, t time.Time, delayRange int, intn func(n int64) int64) time.Time {
+	if s, ok := s.(sourcedSchedule); ok {
+		return s.randomNext(t, delayRange, intn)
+	}
+	return jitter(s, t, delayRange, intn)
+}
+
+// cryptoIntn returns a uniformly random number in [0, n), safe for concurrent
+// use.
+func cryptoIntn(n int64) int64 {
+	x, _ := rand.Int(rand.Reader, big.NewInt(n))
+	return x.Int64()
+}
+
 // dayMatches returns true if the schedule's day-of-week and day-of-month
 // restrictions are satisfied by the given time.
 func dayMatches(s *SpecSchedule, t time.Time) bool {
 	var (
-		domMatch bool = 1<<uint(t.Day())&s.Dom > 0
-		dowMatch bool = 1<<uint(t.Weekday())&s.Dow > 0
+		domMatch bool = 1<<uint(t.Day())&s.Dom > 0 || s.LastDom && isLastDay(t) ||
+			s.NearestWeekday > 0 && isNearestWeekday(s.NearestWeekday, t)
+		dowMatch bool = 1<<uint(t.Weekday())&s.Dow > 0 || 1<<uint(t.Weekday())&s.LastDow > 0 && isLastWeek(t) ||
+			1<<uint(8*int(t.Weekday())+(t.Day()-1)/7+1)&s.NthDow > 0
 	)
-	if s.Dom&starBit > 0 || s.Dow&starBit > 0 {
+	if s.Dom&starBit > 0 || s.Dow&starBit > 0 || s.DayAnd {
 		return domMatch && dowMatch
 	}
 	return domMatch || dowMatch
 }
+
+// isRepeated returns true if t is the second occurrence of its wall-clock
+// time, which happens in the hour repeated when clocks fall back.
+func isRepeated(t time.Time) bool {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, t.Location()).Before(t)
+}
+
+// isLastDay returns true if t falls on the last day of its month.
+func isLastDay(t time.Time) bool {
+	return t.Day() == time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
+
+// isNearestWeekday returns true if t is the weekday nearest, within its month,
+// to one of the days of the month whose bit is set in days.
+func isNearestWeekday(days uint64, t time.Time) bool {
+	if wd := t.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return false
+	}
+	last := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	for d := t.Day() - 2; d <= t.Day()+2; d++ {
+		if d < 1 || d > last || 1<<uint(d)&days == 0 {
+			continue
+		}
+		day := d
+		switch time.Date(t.Year(), t.Month(), d, 0, 0, 0, 0, t.Location()).Weekday() {
+		case time.Saturday:
+			if day--; day < 1 {
+				day = d + 2
+			}
+		case time.Sunday:
+			if day++; day > last {
+				day = d - 2
+			}
+		}
+		if day == t.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// isLastWeek returns true if t falls within the last seven days of its month,
+// i.e. it is the last occurrence of its day of the week in the month.
+func isLastWeek(t time.Time) bool {
+	return t.AddDate(0, 0, 7).Month() != t.Month()
+}
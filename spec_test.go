@@ -2,6 +2,8 @@ package cron
 
 import (
 	"fmt"
+	"math/rand"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -21,6 +23,12 @@ func TestActivation(t *testing.T) {
 		{"Mon Jul 9 15:20 2012", "0 5/15 * * *", true},
 		{"Mon Jul 9 15:50 2012", "0 5/15 * * *", true},
 
+		// Steps over a range.
+		{"Mon Jul 9 15:30 2012", "0 0-30/5 9-17/2 * * ?", true},
+		{"Mon Jul 9 15:35 2012", "0 0-30/5 9-17/2 * * ?", false},
+		{"Mon Jul 9 16:00 2012", "0 0-30/5 9-17/2 * * ?", false},
+		{"Mon Jul 9 17:10 2012", "0 0-30/5 9-17/2 * * ?", true},
+
 		// Named months
 		{"Sun Jul 15 15:00 2012", "0 0/15 * * Jul", true},
 		{"Sun Jul 15 15:00 2012", "0 0/15 * * Jun", false},
@@ -110,8 +118,10 @@ func TestNext(t *testing.T) {
 		// Leap year
 		{"Mon Jul 9 23:35 2012", "0 0 0 29 Feb ?", "Mon Feb 29 00:00 2016"},
 
-		// Daylight savings time 2am EST (-5) -> 3am EDT (-4)
-		{"2012-03-11T00:00:00-0500", "0 30 2 11 Mar ?", "2013-03-11T02:30:00-0400"},
+		// Daylight savings time 2am EST (-5) -> 3am EDT (-4): times in the gap
+		// move forward to the hour after it
+		{"2012-03-11T00:00:00-0500", "0 30 2 11 Mar ?", "2012-03-11T03:30:00-0400"},
+		{"2012-03-11T03:30:00-0400", "0 30 2 11 Mar ?", "2013-03-11T02:30:00-0400"},
 
 		// hourly job
 		{"2012-03-11T00:00:00-0500", "0 0 * * * ?", "2012-03-11T01:00:00-0500"},
@@ -123,21 +133,28 @@ func TestNext(t *testing.T) {
 		{"2012-03-11T00:00:00-0500", "0 0 1 * * ?", "2012-03-11T01:00:00-0500"},
 		{"2012-03-11T01:00:00-0500", "0 0 1 * * ?", "2012-03-12T01:00:00-0400"},
 
-		// 2am nightly job (skipped)
-		{"2012-03-11T00:00:00-0500", "0 0 2 * * ?", "2012-03-12T02:00:00-0400"},
+		// 2am nightly job (moved to 3am)
+		{"2012-03-11T00:00:00-0500", "0 0 2 * * ?", "2012-03-11T03:00:00-0400"},
+		{"2012-03-11T03:00:00-0400", "0 0 2 * * ?", "2012-03-12T02:00:00-0400"},
+		{"2012-03-11T00:00:00-0500", "0 0 2,3 * * ?", "2012-03-11T03:00:00-0400"},
+		{"2012-03-11T03:00:00-0400", "0 0 2,3 * * ?", "2012-03-12T02:00:00-0400"},
 
-		// Daylight savings time 2am EDT (-4) => 1am EST (-5)
+		// Daylight savings time 2am EDT (-4) => 1am EST (-5): times repeated
+		// are activated the first time only
 		{"2012-11-04T00:00:00-0400", "0 30 2 04 Nov ?", "2012-11-04T02:30:00-0500"},
-		{"2012-11-04T01:45:00-0400", "0 30 1 04 Nov ?", "2012-11-04T01:30:00-0500"},
+		{"2012-11-04T00:00:00-0400", "0 30 1 04 Nov ?", "2012-11-04T01:30:00-0400"},
+		{"2012-11-04T01:45:00-0400", "0 30 1 04 Nov ?", "2013-11-04T01:30:00-0500"},
+		{"2012-11-04T01:45:00-0400", "0 */15 1 * * ?", "2012-11-05T01:00:00-0500"},
+		{"2012-11-04T01:30:00-0400", "0 30 * * * ?", "2012-11-04T01:30:00-0500"},
 
 		// hourly job
 		{"2012-11-04T00:00:00-0400", "0 0 * * * ?", "2012-11-04T01:00:00-0400"},
 		{"2012-11-04T01:00:00-0400", "0 0 * * * ?", "2012-11-04T01:00:00-0500"},
 		{"2012-11-04T01:00:00-0500", "0 0 * * * ?", "2012-11-04T02:00:00-0500"},
 
-		// 1am nightly job (runs twice)
+		// 1am nightly job (runs once)
 		{"2012-11-04T00:00:00-0400", "0 0 1 * * ?", "2012-11-04T01:00:00-0400"},
-		{"2012-11-04T01:00:00-0400", "0 0 1 * * ?", "2012-11-04T01:00:00-0500"},
+		{"2012-11-04T01:00:00-0400", "0 0 1 * * ?", "2012-11-05T01:00:00-0500"},
 		{"2012-11-04T01:00:00-0500", "0 0 1 * * ?", "2012-11-05T01:00:00-0500"},
 
 		// 2am nightly job
@@ -194,6 +211,286 @@ func TestRandomNext(t *testing.T) {
 	//}
 }
 
+// Test that a delay range wider than the schedule's interval never pushes a
+// run into the following slot.
+func TestRandomNextStaysWithinSlot(t *testing.T) {
+	tests := []struct {
+		spec       string
+		delayRange int
+	}{
+		{"0 * * * * *", 3600},
+		{"0 */5 * * * *", 82800},
+		{"* * * * * *", 10},
+	}
+
+	from := getTime("Mon Jul 9 14:45:30 2012")
+	for _, test := range tests {
+		sched, err := Parse(test.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		base := sched.Next(from)
+		following := sched.Next(base)
+		for i := 0; i < 200; i++ {
+			actual := sched.RandomNext(from, test.delayRange)
+			if actual.Before(base) || !actual.Before(following) {
+				t.Fatalf("%s with delay %d: %v not within [%v, %v)",
+					test.spec, test.delayRange, actual, base, following)
+			}
+		}
+	}
+}
+
+// dayFirings returns the hours at which sched fires during the day starting
+// at midnight of the given time.
+func dayFirings(sched Schedule, day time.Time) []int {
+	var hours []int
+	VisitSchedule(sched, day.Add(-time.Second), day.Add(24*time.Hour-time.Second), func(t time.Time) {
+		hours = append(hours, t.Hour())
+	})
+	return hours
+}
+
+func TestSteppedHourRanges(t *testing.T) {
+	day := getTime("Mon Jul 9 00:00 2012")
+	tests := []struct {
+		spec     string
+		expected []int
+	}{
+		// A stepped range starting at 1 already covers the whole day for a step of 4.
+		{"0 0 1-23/4 * * *", []int{1, 5, 9, 13, 17, 21}},
+		{"0 0 1/4 * * *", []int{1, 5, 9, 13, 17, 21}},
+		// Starting later does not wrap back to the early hours.
+		{"0 0 5/4 * * *", []int{5, 9, 13, 17, 21}},
+	}
+	for _, test := range tests {
+		sched, err := Parse(test.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := dayFirings(sched, day); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: (expected) %v != %v (actual)", test.spec, test.expected, actual)
+		}
+	}
+}
+
+func TestEveryNHoursFrom(t *testing.T) {
+	day := getTime("Mon Jul 9 00:00 2012")
+	tests := []struct {
+		start, step int
+		expected    []int
+	}{
+		{1, 4, []int{1, 5, 9, 13, 17, 21}},
+		{5, 4, []int{1, 5, 9, 13, 17, 21}},
+		{22, 6, []int{4, 10, 16, 22}},
+		{7, 5, []int{3, 7, 12, 17, 22}},
+		{9, 24, []int{9}},
+	}
+	for _, test := range tests {
+		actual := dayFirings(EveryNHoursFrom(test.start, test.step), day)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("EveryNHoursFrom(%d, %d): (expected) %v != %v (actual)",
+				test.start, test.step, test.expected, actual)
+		}
+	}
+}
+
+func TestEveryNHoursFromInvalid(t *testing.T) {
+	for _, args := range [][2]int{{-1, 4}, {24, 4}, {0, 0}, {0, 25}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("EveryNHoursFrom(%d, %d): expected a panic", args[0], args[1])
+				}
+			}()
+			EveryNHoursFrom(args[0], args[1])
+		}()
+	}
+}
+
+func TestFirstAndLastOfMonth(t *testing.T) {
+	tests := []struct {
+		sched    Schedule
+		expected []string
+	}{
+		{FirstOfMonth(9, 15), []string{
+			"Wed Feb 1 09:15 2012",
+			"Thu Mar 1 09:15 2012",
+			"Sun Apr 1 09:15 2012",
+			"Tue May 1 09:15 2012",
+		}},
+		{LastOfMonth(23, 30), []string{
+			"Tue Jan 31 23:30 2012",
+			"Wed Feb 29 23:30 2012", // Leap year
+			"Sat Mar 31 23:30 2012",
+			"Mon Apr 30 23:30 2012",
+		}},
+		{LastOfMonth(0, 0), []string{
+			"Tue Feb 28 00:00 2013",
+			"Sun Mar 31 00:00 2013",
+		}},
+	}
+	for _, test := range tests {
+		from := getTime(test.expected[0]).AddDate(0, 0, -20)
+		for _, e := range test.expected {
+			actual := test.sched.Next(from)
+			if !actual.Equal(getTime(e)) {
+				t.Errorf("from %v: (expected) %s != %v (actual)", from, e, actual)
+			}
+			from = actual
+		}
+	}
+}
+
+// Test that "L" in the day of month fires on the last day of each month,
+// through leap and common Februaries and 30-day months.
+func TestLastDayOfMonth(t *testing.T) {
+	entries := []struct {
+		spec     string
+		from     string
+		expected []string
+	}{
+		{"0 0 0 L * ?", "Mon Jan 15 12:00 2024", []string{
+			"Wed Jan 31 00:00 2024",
+			"Thu Feb 29 00:00 2024",
+			"Sun Mar 31 00:00 2024",
+			"Tue Apr 30 00:00 2024",
+		}},
+		{"0 0 0 L * ?", "Wed Feb 1 00:00 2023", []string{
+			"Tue Feb 28 00:00 2023",
+			"Fri Mar 31 00:00 2023",
+		}},
+		{"0 0 0 1,l * ?", "Sun Jun 2 00:00 2024", []string{
+			"Sun Jun 30 00:00 2024",
+			"Mon Jul 1 00:00 2024",
+			"Wed Jul 31 00:00 2024",
+		}},
+	}
+	for _, c := range entries {
+		sched, err := Parse(c.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		from := getTime(c.from)
+		for _, e := range c.expected {
+			actual := sched.Next(from)
+			if !actual.Equal(getTime(e)) {
+				t.Errorf("%s from %v: (expected) %s != %v (actual)", c.spec, from, e, actual)
+			}
+			from = actual
+		}
+	}
+}
+
+// Test that "<day>W" fires on the weekday nearest to the day, without leaving
+// its month.
+func TestNearestWeekday(t *testing.T) {
+	entries := []struct {
+		spec, from, expected string
+	}{
+		// The 15th is a Saturday, a Sunday and a Monday.
+		{"0 0 9 15W * ?", "Sat Jun 1 00:00 2024", "Fri Jun 14 09:00 2024"},
+		{"0 0 9 15W * ?", "Sun Sep 1 00:00 2024", "Mon Sep 16 09:00 2024"},
+		{"0 0 9 15W * ?", "Mon Jul 1 00:00 2024", "Mon Jul 15 09:00 2024"},
+		// The 1st is a Saturday and the 31st a Sunday: the nearest weekday
+		// within the month is two days away.
+		{"0 0 9 1W * ?", "Fri May 31 12:00 2024", "Mon Jun 3 09:00 2024"},
+		{"0 0 9 31W * ?", "Fri Mar 1 00:00 2024", "Fri Mar 29 09:00 2024"},
+		// Listed with a plain day.
+		{"0 0 9 1,15w * ?", "Sun Jun 2 00:00 2024", "Fri Jun 14 09:00 2024"},
+	}
+	for _, c := range entries {
+		sched, err := Parse(c.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := sched.Next(getTime(c.from)); !actual.Equal(getTime(c.expected)) {
+			t.Errorf("%s from %s: (expected) %s != %v (actual)", c.spec, c.from, c.expected, actual)
+		}
+	}
+
+	for _, spec := range []string{"0 0 9 32W * ?", "0 0 9 0W * ?", "0 0 9 xW * ?"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("expected an error parsing %s", spec)
+		}
+	}
+}
+
+// Test that "<day>#<n>" fires on the nth occurrence of the day in the month,
+// skipping months that have no such occurrence.
+func TestNthDayOfWeek(t *testing.T) {
+	entries := []struct {
+		spec     string
+		from     string
+		expected []string
+	}{
+		{"0 0 9 ? * FRI#1", "Sat Jun 1 00:00 2024", []string{
+			"Fri Jun 7 09:00 2024",
+			"Fri Jul 5 09:00 2024",
+		}},
+		{"0 0 9 ? * 2#2", "Mon Jul 1 00:00 2024", []string{
+			"Tue Jul 9 09:00 2024",
+			"Tue Aug 13 09:00 2024",
+		}},
+		// November 2024 has only four Mondays.
+		{"0 0 9 * * MON#5", "Fri Nov 1 00:00 2024", []string{
+			"Mon Dec 30 09:00 2024",
+			"Mon Mar 31 09:00 2025",
+		}},
+		{"0 0 9 * * tue#2,fri#1", "Mon Jul 1 00:00 2024", []string{
+			"Fri Jul 5 09:00 2024",
+			"Tue Jul 9 09:00 2024",
+		}},
+	}
+	for _, c := range entries {
+		sched, err := Parse(c.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		from := getTime(c.from)
+		for _, e := range c.expected {
+			actual := sched.Next(from)
+			if !actual.Equal(getTime(e)) {
+				t.Errorf("%s from %v: (expected) %s != %v (actual)", c.spec, from, e, actual)
+			}
+			from = actual
+		}
+	}
+
+	for _, spec := range []string{"0 0 9 * * MON#6", "0 0 9 * * MON#0", "0 0 9 * * 7#1", "0 0 9 * * FOO#1"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("expected an error parsing %s", spec)
+		}
+	}
+}
+
+// Test that "<day>L" fires on the last occurrence of that day in the month,
+// whether it falls on the 31st or a week earlier.
+func TestLastDayOfWeek(t *testing.T) {
+	expected := []string{
+		"Fri May 31 18:00 2024",
+		"Fri Jun 28 18:00 2024",
+		"Fri Jul 26 18:00 2024",
+		"Fri Aug 30 18:00 2024",
+		"Fri Sep 27 18:00 2024",
+		"Fri Oct 25 18:00 2024",
+	}
+	for _, spec := range []string{"0 0 18 ? * 5L", "0 0 18 * * FRIL"} {
+		sched, err := Parse(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		from := getTime("Mon Apr 29 00:00 2024")
+		for _, e := range expected {
+			actual := sched.Next(from)
+			if !actual.Equal(getTime(e)) {
+				t.Errorf("%s from %v: (expected) %s != %v (actual)", spec, from, e, actual)
+			}
+			from = actual
+		}
+	}
+}
+
 func TestErrors(t *testing.T) {
 	invalidSpecs := []string{
 		"xyz",
@@ -275,3 +572,170 @@ func getTimeTZ(value string) time.Time {
 
 	return t
 }
+
+// Test that the random delay is uniform over [0, delayRange), using a seeded
+// source so that the result is reproducible.
+func TestRandomNextDistribution(t *testing.T) {
+	const (
+		delayRange = 100
+		buckets    = 10
+		samples    = 10000
+	)
+	sched, err := Parse("0 0 0 * * ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := sched.(*SpecSchedule)
+	base := getTime("Mon Jul 9 12:00 2012")
+	next := spec.Next(base)
+
+	seeded := func(seed int64) func(int64) int64 {
+		return rand.New(rand.NewSource(seed)).Int63n
+	}
+	a, b := seeded(1), seeded(1)
+	for i := 0; i < 100; i++ {
+		if x, y := spec.randomNext(base, delayRange, a), spec.randomNext(base, delayRange, b); !x.Equal(y) {
+			t.Fatalf("expected the same result for the same seed, got %v and %v", x, y)
+		}
+	}
+
+	var counts [buckets]int
+	intn := seeded(42)
+	for i := 0; i < samples; i++ {
+		delay := spec.randomNext(base, delayRange, intn).Sub(next)
+		if delay < 0 || delay >= delayRange*time.Second {
+			t.Fatalf("delay %v out of range [0, %ds)", delay, delayRange)
+		}
+		counts[int(delay/time.Second)*buckets/delayRange]++
+	}
+
+	// Chi-square with 9 degrees of freedom; 27.88 is the p = 0.001 critical value.
+	expected := float64(samples) / buckets
+	var chi2 float64
+	for _, n := range counts {
+		d := float64(n) - expected
+		chi2 += d * d / expected
+	}
+	if chi2 > 27.88 {
+		t.Errorf("delays are not uniform (chi-square %.2f): %v", chi2, counts)
+	}
+}
+
+// Test that DayAnd fires on the intersection of the day fields, where
+// standard cron fires on their union.
+func TestDayAnd(t *testing.T) {
+	spec := "0 0 0 13 * 5"
+	from := getTime("Mon Jan 1 00:00 2024")
+	entries := []struct {
+		parser   Parser
+		expected []string
+	}{
+		{NewParser(Second | Minute | Hour | Dom | Month | Dow), []string{
+			"Fri Jan 5 00:00 2024", "Fri Jan 12 00:00 2024", "Sat Jan 13 00:00 2024", "Fri Jan 19 00:00 2024",
+		}},
+		{NewParser(Second | Minute | Hour | Dom | Month | Dow | DayAnd), []string{
+			"Fri Sep 13 00:00 2024", "Fri Dec 13 00:00 2024", "Fri Jun 13 00:00 2025", "Fri Feb 13 00:00 2026",
+		}},
+	}
+
+	for _, c := range entries {
+		sched, err := c.parser.Parse(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		next := from
+		for _, e := range c.expected {
+			next = sched.Next(next)
+			if expected := getTime(e); !next.Equal(expected) {
+				t.Errorf("%+v: expected %v, got %v", c.parser.Features(), expected, next)
+			}
+		}
+	}
+}
+
+// Test that a parser with SecondOptional accepts both standard 5-field specs
+// and specs with a leading seconds field.
+func TestOptionalSeconds(t *testing.T) {
+	parser := NewParser(SecondOptional | Minute | Hour | Dom | Month | Dow | Descriptor)
+	entries := []struct {
+		spec     string
+		from     string
+		expected []string
+	}{
+		{"*/15 * * * * *", "Mon Jul 9 14:59:50 2012", []string{
+			"Mon Jul 9 15:00:00 2012", "Mon Jul 9 15:00:15 2012", "Mon Jul 9 15:00:30 2012",
+			"Mon Jul 9 15:00:45 2012", "Mon Jul 9 15:01:00 2012",
+		}},
+		{"*/20 * * * *", "Mon Jul 9 14:59:50 2012", []string{
+			"Mon Jul 9 15:00:00 2012", "Mon Jul 9 15:20:00 2012", "Mon Jul 9 15:40:00 2012",
+		}},
+		{"30 8 * * Mon", "Mon Jul 9 08:30:00 2012", []string{
+			"Mon Jul 16 08:30:00 2012", "Mon Jul 23 08:30:00 2012",
+		}},
+	}
+
+	for _, c := range entries {
+		sched, err := parser.Parse(c.spec)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		next := getTime(c.from)
+		for _, e := range c.expected {
+			next = sched.Next(next)
+			if !next.Equal(getTime(e)) {
+				t.Errorf("%s: expected %s, got %v", c.spec, e, next)
+			}
+		}
+	}
+}
+
+// Test that String writes a schedule as a canonical spec, which parses back
+// to the same schedule and so is stable.
+func TestSpecScheduleString(t *testing.T) {
+	tests := []struct {
+		spec, expected string
+	}{
+		{"* * * * * ?", "* * * * * *"},
+		{"0 */15 * * * ?", "0 */15 * * * *"},
+		{"0 0-30/5 9-17/2 * * ?", "0 0-30/5 9-17/2 * * *"},
+		{"0 5/15 * * * ?", "0 5-50/15 * * * *"},
+		{"30 0 9 * * MON-FRI", "30 0 9 * * 1-5"},
+		{"0 0 0 1,15 JAN,JUL ?", "0 0 0 1,15 1,7 *"},
+		{"0 0 0 1,2,3,5 * ?", "0 0 0 1-3,5 * *"},
+		{"0 0 12 L * ?", "0 0 12 L * *"},
+		{"0 0 12 1,L * ?", "0 0 12 1,L * *"},
+		{"0 0 9 15W * ?", "0 0 9 15W * *"},
+		{"0 0 9 ? * FRIL", "0 0 9 * * 5L"},
+		{"0 0 9 ? * 2#2,TUE#4", "0 0 9 * * 2#2,2#4"},
+		{"0 0 9 1 * MON", "0 0 9 1 * 1"},
+		{"@daily", "0 0 0 * * *"},
+		{"@every 90m", "@every 1h30m0s"},
+		{"CRON_TZ=Asia/Tokyo 0 30 9 * * ?", "CRON_TZ=Asia/Tokyo 0 30 9 * * *"},
+	}
+	for _, c := range tests {
+		schedule, err := Parse(c.spec)
+		if err != nil {
+			t.Errorf("%s: %v", c.spec, err)
+			continue
+		}
+		actual := fmt.Sprintf("%s", schedule)
+		if actual != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.spec, c.expected, actual)
+			continue
+		}
+		reparsed, err := Parse(actual)
+		if err != nil {
+			t.Errorf("%s: %q does not parse: %v", c.spec, actual, err)
+			continue
+		}
+		if !reflect.DeepEqual(reparsed, schedule) {
+			t.Errorf("%s: %q parses to %v, expected %v", c.spec, actual, reparsed, schedule)
+		}
+	}
+
+	custom := InLocation(&ZeroSchedule{}, time.UTC)
+	if _, ok := custom.(fmt.Stringer); ok {
+		t.Error("expected no String method for a custom schedule in a location")
+	}
+}
@@ -0,0 +1,70 @@
+package cron
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// unionSchedule activates whenever any of its schedules does.
+type unionSchedule []Schedule
+
+// Union returns a schedule that activates whenever any of the given schedules
+// does. An activation shared by several of them is returned once. Unlike
+// adding one entry per schedule, the job runs as a single entry, so its
+// runs, skips and name are tracked together.
+func Union(schedules ...Schedule) Schedule {
+	return unionSchedule(schedules)
+}
+
+// timeOfDayParser parses the patterns of TimesOfDay.
+var timeOfDayParser = NewParser(Minute | Hour)
+
+// TimesOfDay returns a daily schedule that is the union of several patterns,
+// each made of a minute and an hour field in cron syntax. For example
+//
+//	TimesOfDay("0 9-11", "*/15 14-16")
+//
+// activates on the hour from 09:00 to 11:00 and every 15 minutes from 14:00
+// to 16:45, which no single cron expression can express.
+func TimesOfDay(patterns ...string) (Schedule, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("cron: no time of day patterns")
+	}
+	schedules := make([]Schedule, len(patterns))
+	for i, pattern := range patterns {
+		s, err := timeOfDayParser.Parse(pattern)
+		if err != nil {
+			return nil, err
+		}
+		schedules[i] = s
+	}
+	return Union(schedules...), nil
+}
+
+// String describes the union as the schedules it is made of, separated by
+// " | ", e.g. "0 0 9-11 * * * | 0 */15 14-16 * * *".
+func (u unionSchedule) String() string {
+	parts := make([]string, len(u))
+	for i, s := range u {
+		parts[i] = scheduleString(s)
+	}
+	return strings.Join(parts, " | ")
+}
+
+// Next returns the soonest activation among the schedules after t, or the zero
+// time if none of them activates again.
+func (u unionSchedule) Next(t time.Time) time.Time {
+	var next time.Time
+	for _, s := range u {
+		if n := s.Next(t); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	return next
+}
+
+// RandomNext delays the union's next activation the way jitter does.
+func (u unionSchedule) RandomNext(t time.Time, delayRange int) time.Time {
+	return jitter(u, t, delayRange, cryptoIntn)
+}
@@ -0,0 +1,61 @@
+package cron
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTimesOfDay(t *testing.T) {
+	sched, err := TimesOfDay("0 9-11", "*/15 14-16")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []time.Time
+	VisitSchedule(sched, getTime("Mon Jul 9 00:00 2012"), getTime("Tue Jul 10 00:00 2012"),
+		func(t time.Time) { actual = append(actual, t) })
+
+	expected := []string{"Mon Jul 9 09:00 2012", "Mon Jul 9 10:00 2012", "Mon Jul 9 11:00 2012"}
+	for h := 14; h <= 16; h++ {
+		for m := 0; m < 60; m += 15 {
+			expected = append(expected, fmt.Sprintf("Mon Jul 9 %02d:%02d 2012", h, m))
+		}
+	}
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d activations, got %d: %v", len(expected), len(actual), actual)
+	}
+	for i, e := range expected {
+		if !actual[i].Equal(getTime(e)) {
+			t.Errorf("activation %d: (expected) %s != %s (actual)", i, e, actual[i])
+		}
+	}
+}
+
+func TestTimesOfDayInvalid(t *testing.T) {
+	if _, err := TimesOfDay(); err == nil {
+		t.Error("expected an error without patterns")
+	}
+	if _, err := TimesOfDay("0 9", "0 25"); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+// Test that an activation shared by several schedules is returned once, and
+// that unsatisfiable schedules are ignored.
+func TestUnionOverlap(t *testing.T) {
+	hourly, _ := Parse("0 0 * * * ?")
+	halfHourly, _ := Parse("0 0,30 * * * ?")
+	sched := Union(hourly, halfHourly, &ZeroSchedule{})
+
+	next := getTime("Mon Jul 9 09:00 2012")
+	for _, e := range []string{"Mon Jul 9 09:30 2012", "Mon Jul 9 10:00 2012", "Mon Jul 9 10:30 2012"} {
+		next = sched.Next(next)
+		if !next.Equal(getTime(e)) {
+			t.Errorf("expected %s, got %v", e, next)
+		}
+	}
+	if next := Union(&ZeroSchedule{}).Next(next); !next.IsZero() {
+		t.Errorf("expected the zero time, got %v", next)
+	}
+}
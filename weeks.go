@@ -0,0 +1,84 @@
+package cron
+
+import (
+	"fmt"
+	"time"
+)
+
+// weeksSchedule activates at a time of day on one weekday every n weeks,
+// counting from the first such weekday on or after an anchor date.
+type weeksSchedule struct {
+	first        civilDate
+	weeks        int
+	hour, minute int
+}
+
+// civilDate is a calendar date without a location.
+type civilDate struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+// days returns the number of days from the Unix epoch to d.
+func (d civilDate) days() int {
+	return int(time.Date(d.year, d.month, d.day, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+// EveryNWeeks returns a schedule that activates at hour:min on the given
+// weekday every n weeks. The first activation is on the first such weekday on
+// or after the date of anchor, taken in the anchor's location; only its date
+// matters, and activations before it are never returned. The time of day is
+// interpreted in the location of the time passed to Next, so that it follows
+// the Cron's time zone.
+//
+// It panics if n is less than 1, or hour or min is out of range.
+func EveryNWeeks(n int, anchor time.Time, weekday time.Weekday, hour, min int) Schedule {
+	if n < 1 || hour < 0 || hour > 23 || min < 0 || min > 59 || weekday < time.Sunday || weekday > time.Saturday {
+		panic("cron: EveryNWeeks requires n of at least 1, a valid weekday, hour in 0-23 and min in 0-59")
+	}
+	offset := (int(weekday) - int(anchor.Weekday()) + 7) % 7
+	y, m, d := anchor.AddDate(0, 0, offset).Date()
+	return &weeksSchedule{
+		first:  civilDate{y, m, d},
+		weeks:  n,
+		hour:   hour,
+		minute: min,
+	}
+}
+
+// BiWeekly returns a schedule that activates at hour:min on the given weekday
+// every other week, counting from anchor. See EveryNWeeks.
+func BiWeekly(anchor time.Time, weekday time.Weekday, hour, min int) Schedule {
+	return EveryNWeeks(2, anchor, weekday, hour, min)
+}
+
+// String describes the schedule, e.g. "every 2 weeks on Monday at 09:30 from
+// 2024-01-01".
+func (s *weeksSchedule) String() string {
+	first := time.Date(s.first.year, s.first.month, s.first.day, 0, 0, 0, 0, time.UTC)
+	return fmt.Sprintf("every %d weeks on %s at %02d:%02d from %s",
+		s.weeks, first.Weekday(), s.hour, s.minute, first.Format("2006-01-02"))
+}
+
+// Next returns the first activation after t.
+func (s *weeksSchedule) Next(t time.Time) time.Time {
+	period := 7 * s.weeks
+	y, m, d := t.Date()
+	k := 0
+	if diff := (civilDate{y, m, d}).days() - s.first.days(); diff > 0 {
+		k = diff / period
+	}
+	for {
+		next := time.Date(s.first.year, s.first.month, s.first.day+k*period, s.hour, s.minute, 0, 0, t.Location())
+		if next.After(t) {
+			return next
+		}
+		k++
+	}
+}
+
+// RandomNext delays Next by up to delayRange seconds; see jitter.
+func (s *weeksSchedule) RandomNext(t time.Time, delayRange int) time.Time {
+	return jitter(s, t, delayRange, cryptoIntn)
+}
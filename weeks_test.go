@@ -0,0 +1,67 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBiWeekly(t *testing.T) {
+	// Anchored on a Wednesday, so the first Monday is the following week.
+	sched := BiWeekly(getTime("Wed Nov 20 15:04 2024"), time.Monday, 9, 30)
+
+	expected := []string{
+		"Mon Nov 25 09:30 2024",
+		"Mon Dec 9 09:30 2024",
+		"Mon Dec 23 09:30 2024",
+		"Mon Jan 6 09:30 2025",
+		"Mon Jan 20 09:30 2025",
+		"Mon Feb 3 09:30 2025",
+	}
+	next := getTime("Fri Nov 1 00:00 2024")
+	for _, e := range expected {
+		next = sched.Next(next)
+		if !next.Equal(getTime(e)) {
+			t.Errorf("expected %s, got %v", e, next)
+		}
+	}
+
+	// Starting mid-cycle, on a Monday that is skipped and on an activation day.
+	for _, c := range []struct{ from, expected string }{
+		{"Mon Dec 16 09:30 2024", "Mon Dec 23 09:30 2024"},
+		{"Mon Dec 23 09:29 2024", "Mon Dec 23 09:30 2024"},
+		{"Mon Dec 23 09:30 2024", "Mon Jan 6 09:30 2025"},
+	} {
+		if actual := sched.Next(getTime(c.from)); !actual.Equal(getTime(c.expected)) {
+			t.Errorf("from %s: expected %s, got %v", c.from, c.expected, actual)
+		}
+	}
+}
+
+func TestEveryNWeeks(t *testing.T) {
+	sched := EveryNWeeks(3, getTime("Sun Dec 29 00:00 2024"), time.Sunday, 0, 0)
+	next := getTime("Sun Dec 29 00:00 2024").Add(-time.Second)
+	for _, e := range []string{"Sun Dec 29 00:00 2024", "Sun Jan 19 00:00 2025", "Sun Feb 9 00:00 2025", "Sun Mar 2 00:00 2025"} {
+		next = sched.Next(next)
+		if !next.Equal(getTime(e)) {
+			t.Errorf("expected %s, got %v", e, next)
+		}
+	}
+
+	delayed := sched.RandomNext(next, 3600)
+	if delayed.Before(sched.Next(next)) || !delayed.Before(sched.Next(next).Add(time.Hour)) {
+		t.Errorf("expected a delay within an hour of %v, got %v", sched.Next(next), delayed)
+	}
+}
+
+func TestEveryNWeeksInvalid(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a panic for n = %d", n)
+				}
+			}()
+			EveryNWeeks(n, time.Now(), time.Monday, 9, 0)
+		}()
+	}
+}
@@ -0,0 +1,50 @@
+package cron
+
+import "time"
+
+// windowSchedule activates like its schedule, but only within a window.
+type windowSchedule struct {
+	schedule            Schedule
+	notBefore, notAfter time.Time
+}
+
+// Between returns a schedule that activates like s, but only from notBefore
+// to notAfter inclusive, e.g. for a job that runs only in December. A zero
+// notBefore or notAfter leaves the window open on that side. Once notAfter
+// has passed, the schedule returns the zero time.
+func Between(s Schedule, notBefore, notAfter time.Time) Schedule {
+	return windowSchedule{s, notBefore, notAfter}
+}
+
+// String describes the schedule, e.g. "0 0 9 * * * from
+// 2012-12-01T00:00:00Z until 2012-12-31T23:59:59Z".
+func (w windowSchedule) String() string {
+	s := scheduleString(w.schedule)
+	if !w.notBefore.IsZero() {
+		s += " from " + w.notBefore.Format(time.RFC3339)
+	}
+	if !w.notAfter.IsZero() {
+		s += " until " + w.notAfter.Format(time.RFC3339)
+	}
+	return s
+}
+
+// Next returns the first activation of the schedule after t that falls within
+// the window, or the zero time if there is none.
+func (w windowSchedule) Next(t time.Time) time.Time {
+	// Searching from just before notBefore keeps an activation right at it.
+	if !w.notBefore.IsZero() && t.Before(w.notBefore) {
+		t = w.notBefore.Add(-time.Nanosecond)
+	}
+	next := w.schedule.Next(t)
+	if !w.notAfter.IsZero() && next.After(w.notAfter) {
+		return time.Time{}
+	}
+	return next
+}
+
+// RandomNext delays the next activation within the window the way jitter
+// does. The delay of the last activation may take it past notAfter.
+func (w windowSchedule) RandomNext(t time.Time, delayRange int) time.Time {
+	return jitter(w, t, delayRange, cryptoIntn)
+}
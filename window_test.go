@@ -0,0 +1,65 @@
+package cron
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBetween(t *testing.T) {
+	daily, _ := Parse("0 0 9 * * ?")
+	s := Between(daily, getTime("Sat Dec 1 00:00 2012"), getTime("Mon Dec 31 23:59:59 2012"))
+
+	tests := []struct {
+		time, expected string
+	}{
+		{"Mon Jul 9 14:45 2012", "Sat Dec 1 09:00 2012"},
+		{"Sat Dec 1 09:00 2012", "Sun Dec 2 09:00 2012"},
+		{"Sun Dec 30 12:00 2012", "Mon Dec 31 09:00 2012"},
+		{"Mon Dec 31 09:00 2012", ""},
+		{"Tue Jan 1 12:00 2013", ""},
+	}
+	for _, c := range tests {
+		actual := s.Next(getTime(c.time))
+		if expected := getTime(c.expected); !actual.Equal(expected) {
+			t.Errorf("%s: expected %v, got %v", c.time, expected, actual)
+		}
+	}
+
+	// An activation right at either end is kept.
+	s = Between(daily, getTime("Sat Dec 1 09:00 2012"), getTime("Sun Dec 2 09:00 2012"))
+	if next := s.Next(getTime("Mon Jul 9 14:45 2012")); !next.Equal(getTime("Sat Dec 1 09:00 2012")) {
+		t.Errorf("expected an activation at the start of the window, got %v", next)
+	}
+	if next := s.Next(getTime("Sat Dec 1 09:00 2012")); !next.Equal(getTime("Sun Dec 2 09:00 2012")) {
+		t.Errorf("expected an activation at the end of the window, got %v", next)
+	}
+
+	// A zero bound leaves the window open.
+	s = Between(daily, time.Time{}, getTime("Sat Dec 1 00:00 2012"))
+	if next := s.Next(getTime("Mon Jul 9 14:45 2012")); !next.Equal(getTime("Tue Jul 10 09:00 2012")) {
+		t.Errorf("expected an activation with an open start, got %v", next)
+	}
+}
+
+// Test that an entry with a window runs only within it, and is left with a
+// zero Next after its end.
+func TestBetweenEntry(t *testing.T) {
+	hourly, _ := Parse("0 0 * * * ?")
+	start := getTime("Sat Dec 1 00:00 2012")
+	job := &countJob{}
+	cron := NewWithLocation(start.Location())
+	cron.NameAndDelaySchedule("december", Between(hourly, start, start.Add(3*time.Hour)), 0, job)
+
+	for now := start.Add(-2 * time.Hour); now.Before(start.Add(6 * time.Hour)); now = now.Add(30 * time.Minute) {
+		cron.Tick(now)
+	}
+	cron.jobs.Wait()
+	if runs := atomic.LoadInt32(&job.runs); runs != 4 {
+		t.Errorf("expected 4 runs within the window, got %d", runs)
+	}
+	e, _ := cron.GetEntry("december")
+	if !e.Prev.Equal(start.Add(3*time.Hour)) || !e.Next.IsZero() {
+		t.Errorf("expected the last run at the end of the window and a zero Next, got %v then %v", e.Prev, e.Next)
+	}
+}